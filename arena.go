@@ -0,0 +1,63 @@
+package goparsify
+
+// arena hands out the .Child slices of results from a few big slabs, instead of allocating every slice separately.
+// Combinators that backtrack reset it to a mark taken before trying, so the results of paths that failed are
+// reused rather than thrown away. Slabs are kept once allocated, so a State that is reused doesnt allocate
+// them again.
+type arena struct {
+	slabs [][]Result
+	// slab is the index of the slab being allocated from, used is how much of it has been handed out
+	slab, used int
+}
+
+type arenaMark struct {
+	slab, used int
+}
+
+// Slabs start small so small inputs dont pay for a big slab, and double up to maxSlab results. Bigger slabs
+// mean fewer allocations, but more memory wasted at the end of each slab.
+const (
+	minSlab = 16
+	maxSlab = 128
+)
+
+// alloc returns n zeroed results. The slice is capped at n, so appending to it never overwrites another slice.
+func (a *arena) alloc(n int, input string) []Result {
+	for a.slab >= len(a.slabs) || a.used+n > len(a.slabs[a.slab]) {
+		if a.slab < len(a.slabs) {
+			a.slab++
+			a.used = 0
+		}
+		if a.slab == len(a.slabs) {
+			size := minSlab
+			if len(a.slabs) > 0 {
+				size = 2 * len(a.slabs[len(a.slabs)-1])
+			}
+			if size > maxSlab {
+				size = maxSlab
+			}
+			if n > size {
+				size = n
+			}
+			a.slabs = append(a.slabs, make([]Result, size))
+		}
+	}
+
+	current := a.slabs[a.slab]
+	results := current[a.used : a.used+n : a.used+n]
+	a.used += n
+	for i := range results {
+		results[i] = Result{Input: input}
+	}
+	return results
+}
+
+func (a *arena) mark() arenaMark {
+	return arenaMark{slab: a.slab, used: a.used}
+}
+
+// reset makes everything allocated since m available again
+func (a *arena) reset(m arenaMark) {
+	a.slab = m.slab
+	a.used = m.used
+}
@@ -0,0 +1,62 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArena(t *testing.T) {
+	t.Run("allocs zeroed capped slices", func(t *testing.T) {
+		a := arena{}
+		first := a.alloc(2, "input")
+		first[0].Token = "a"
+		first = append(first, Result{Token: "c"})
+		second := a.alloc(2, "input")
+
+		require.Equal(t, []Result{{Input: "input"}, {Input: "input"}}, second)
+		require.Equal(t, "a", first[0].Token)
+	})
+
+	t.Run("reuses after reset", func(t *testing.T) {
+		a := arena{}
+		a.alloc(3, "")
+		mark := a.mark()
+		failed := a.alloc(2, "")
+		failed[0].Token = "failed"
+
+		a.reset(mark)
+		again := a.alloc(2, "")
+		require.Equal(t, "", again[0].Token)
+		require.Equal(t, &failed[0], &again[0])
+	})
+
+	t.Run("starts new slabs", func(t *testing.T) {
+		a := arena{}
+		small := a.alloc(maxSlab-1, "")
+		mark := a.mark()
+		big := a.alloc(maxSlab*2, "")
+		require.Len(t, big, maxSlab*2)
+		require.Len(t, a.slabs, 2)
+
+		// the big slab only held results allocated after the mark, so it is reused
+		a.reset(mark)
+		a.alloc(1, "")
+		reused := a.alloc(1, "")
+		require.Equal(t, &big[0], &reused[0])
+		require.Len(t, a.slabs, 2)
+		require.Len(t, small, maxSlab-1)
+	})
+}
+
+func TestArenaBacktracking(t *testing.T) {
+	// every failed branch allocates children that must not end up in the result
+	var value Parser
+	list := Seq("(", ZeroOrMore(&value, ","), ")")
+	pair := Seq(Chars("a-z"), "=", &value)
+	value = Any(Seq(Chars("a-z"), ":", "bad"), pair, Chars("a-z"), NumberLit(), list)
+
+	result, err := Parse(value, "(a = (b, c = 1, 2), d, (e = f))")
+	require.NoError(t, err)
+	require.Equal(t, "[(,[[a,=,[(,[b,[c,=,1],2],)]],d,[(,[[e,=,f]],)]],)]", result.String())
+}
@@ -0,0 +1,228 @@
+package goparsify
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// The binary parsers match bytes rather than text, so none of them skip whitespace. Combinators like Any still do
+// though, so binary grammars should be run with NoWhitespace or be inside of NoAutoWS. RunBytes does the former,
+// and runs over a []byte without copying it.
+
+// Byte matches any one byte, and sets .Result to it as a byte
+func Byte() Parser {
+	g := bytesGrammar("Byte()", 1)
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		if ps.Pos >= len(ps.Input) {
+			ps.ErrorHere("byte")
+			return
+		}
+		node.Start = ps.Pos
+		node.End = ps.Pos + 1
+		node.Result = ps.Input[ps.Pos]
+		ps.Advance(1)
+	})
+}
+
+// Bytes matches the next n bytes, whatever they are. .Token is the bytes matched.
+func Bytes(n int) Parser {
+	g := bytesGrammar(fmt.Sprintf("Bytes(%d)", n), n)
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		if !takeBytes(ps, node, n) {
+			ps.ErrorHere(fmt.Sprintf("%d bytes", n))
+		}
+	})
+}
+
+// bytesGrammar describes a parser that matches n bytes
+func bytesGrammar(name string, n int) grammarFunc {
+	return func() *Grammar {
+		return &Grammar{Kind: GrammarBytes, Name: name, Min: n, Max: n}
+	}
+}
+
+// takeBytes matches the next n bytes, or returns false if there arent that many left
+func takeBytes(ps *State, node *Result, n int) bool {
+	if n < 0 || n > len(ps.Input)-ps.Pos {
+		return false
+	}
+	node.Start = ps.Pos
+	node.End = ps.Pos + n
+	node.Token = ps.Input[node.Start:node.End]
+	ps.Advance(n)
+	return true
+}
+
+// Uint16 matches a 2 byte unsigned integer in the given byte order, eg binary.BigEndian, and sets .Result to it
+// as a uint16
+func Uint16(order binary.ByteOrder) Parser {
+	return fixedWidth("Uint16", 2, order, func(b []byte) interface{} { return order.Uint16(b) })
+}
+
+// Uint32 matches a 4 byte unsigned integer in the given byte order, and sets .Result to it as a uint32
+func Uint32(order binary.ByteOrder) Parser {
+	return fixedWidth("Uint32", 4, order, func(b []byte) interface{} { return order.Uint32(b) })
+}
+
+// Uint64 matches an 8 byte unsigned integer in the given byte order, and sets .Result to it as a uint64
+func Uint64(order binary.ByteOrder) Parser {
+	return fixedWidth("Uint64", 8, order, func(b []byte) interface{} { return order.Uint64(b) })
+}
+
+func fixedWidth(name string, width int, order binary.ByteOrder, decode func(b []byte) interface{}) Parser {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarBytes, Name: name + "(" + order.String() + ")", Literal: order.String(), Min: width, Max: width}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		if width > len(ps.Input)-ps.Pos {
+			ps.ErrorHere(fmt.Sprintf("%d bytes", width))
+			return
+		}
+		var buf [8]byte
+		copy(buf[:], ps.Input[ps.Pos:ps.Pos+width])
+		node.Start = ps.Pos
+		node.End = ps.Pos + width
+		node.Result = decode(buf[:width])
+		ps.Advance(width)
+	})
+}
+
+// Uvarint matches an unsigned varint, as used by protobuf and also known as unsigned LEB128, and sets .Result
+// to it as a uint64
+func Uvarint() Parser {
+	return varint("uvarint", false, func(v uint64, n int) interface{} { return v })
+}
+
+// Varint matches a zigzag encoded signed varint, as used by protobuf's sint types and encoding/binary, and sets
+// .Result to it as an int64
+func Varint() Parser {
+	return varint("varint", false, func(v uint64, n int) interface{} { return int64(v>>1) ^ -int64(v&1) })
+}
+
+// LEB128 matches a signed LEB128 number, as used by wasm and DWARF, and sets .Result to it as an int64
+func LEB128() Parser {
+	return varint("leb128", true, func(v uint64, n int) interface{} {
+		// sign extend from the top bit of the last group
+		if shift := uint(7 * n); shift < 64 && v&(1<<(shift-1)) != 0 {
+			return int64(v) | -1<<shift
+		}
+		return int64(v)
+	})
+}
+
+// varint matches 7 bits a byte, least significant group first, while the top bit of each byte is set. Anything
+// that doesnt fit in 64 bits is an error, for signed LEB128 that means the last of 10 bytes has to be all sign.
+func varint(name string, signed bool, decode func(v uint64, n int) interface{}) Parser {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarVarint, Name: name, Literal: name}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		var v uint64
+		for i := 0; i < binary.MaxVarintLen64 && ps.Pos+i < len(ps.Input); i++ {
+			b := ps.Input[ps.Pos+i]
+			if i == binary.MaxVarintLen64-1 && b > 1 && !(signed && b == 0x7f) {
+				break
+			}
+			v |= uint64(b&0x7f) << uint(7*i)
+			if b < 0x80 {
+				node.Start = ps.Pos
+				node.End = ps.Pos + i + 1
+				node.Result = decode(v, i+1)
+				ps.Advance(i + 1)
+				return
+			}
+		}
+		ps.ErrorHere(name)
+	})
+}
+
+// Take matches length followed by as many bytes as it says, eg a length prefixed string. The length parser must
+// set .Result to an integer, like Byte, Uint32 or Uvarint do. .Token is the bytes after the length.
+func Take(length Parserish) Parser {
+	lengthParser := Parsify(length)
+
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarTake, Name: "Take()", parsers: []Parser{lengthParser}}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		startpos := ps.Pos
+		lengthResult := Result{Input: node.Input}
+		lengthParser(ps, &lengthResult)
+		if ps.Errored() {
+			return
+		}
+
+		n, ok := toLength(lengthResult.Result)
+		if !ok || !takeBytes(ps, node, n) {
+			ps.ErrorHere(fmt.Sprintf("%v bytes", lengthResult.Result))
+			ps.Pos = startpos
+		}
+	})
+}
+
+// toLength converts the result of a length parser to an int
+func toLength(v interface{}) (int, bool) {
+	var n uint64
+	switch v := v.(type) {
+	case byte:
+		n = uint64(v)
+	case uint16:
+		n = uint64(v)
+	case uint32:
+		n = uint64(v)
+	case uint64:
+		n = v
+	case int64:
+		if v < 0 {
+			return 0, false
+		}
+		n = uint64(v)
+	case int:
+		if v < 0 {
+			return 0, false
+		}
+		n = uint64(v)
+	default:
+		return 0, false
+	}
+	if n > uint64(^uint(0)>>1) {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// RunBytes is Run over a []byte, without copying it into a string. Tokens point into input, so it must not be
+// changed while the result is in use. The whitespace parser defaults to NoWhitespace, as binary formats dont
+// have any.
+func RunBytes(parser Parserish, input []byte, ws ...VoidParser) (result interface{}, err error) {
+	if len(ws) == 0 {
+		ws = []VoidParser{NoWhitespace}
+	}
+	return Run(parser, bytesToString(input), ws...)
+}
+
+// RunBytes is Run over a []byte, without copying it into a string. Unlike the package level RunBytes it uses
+// RunOptions.WS, so set that to NoWhitespace for binary formats.
+func (r *Runner) RunBytes(input []byte) (result interface{}, err error) {
+	return r.run(bytesToString(input), r.opts.User)
+}
+
+func bytesToString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
@@ -0,0 +1,175 @@
+package goparsify
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestByte(t *testing.T) {
+	result, p := runParser("\x00\xff", Seq(Byte(), Byte()))
+	require.Equal(t, byte(0), result.Child[0].Result)
+	require.Equal(t, byte(0xff), result.Child[1].Result)
+	require.Equal(t, "", p.Get())
+
+	_, p = runParser("", Byte())
+	require.Equal(t, "offset 0: expected byte", p.Error.Error())
+}
+
+func TestBytes(t *testing.T) {
+	result, p := runParser(" \x01\x02\x03", Bytes(2))
+	require.Equal(t, " \x01", result.Token)
+	require.Equal(t, "\x02\x03", p.Get())
+
+	_, p = runParser("ab", Bytes(3))
+	require.Equal(t, "offset 0: expected 3 bytes", p.Error.Error())
+	require.Equal(t, 0, p.Pos)
+}
+
+func TestFixedWidth(t *testing.T) {
+	input := "\x01\x02\x03\x04\x05\x06\x07\x08"
+	for _, tc := range []struct {
+		parser   Parser
+		expected interface{}
+	}{
+		{Uint16(binary.BigEndian), uint16(0x0102)},
+		{Uint16(binary.LittleEndian), uint16(0x0201)},
+		{Uint32(binary.BigEndian), uint32(0x01020304)},
+		{Uint32(binary.LittleEndian), uint32(0x04030201)},
+		{Uint64(binary.BigEndian), uint64(0x0102030405060708)},
+		{Uint64(binary.LittleEndian), uint64(0x0807060504030201)},
+	} {
+		result, _ := runParser(input, tc.parser)
+		require.Equal(t, tc.expected, result.Result)
+	}
+
+	_, p := runParser("\x01\x02\x03", Uint32(binary.BigEndian))
+	require.Equal(t, "offset 0: expected 4 bytes", p.Error.Error())
+}
+
+func TestVarints(t *testing.T) {
+	t.Run("uvarint", func(t *testing.T) {
+		for _, v := range []uint64{0, 1, 127, 128, 300, math.MaxUint32, math.MaxUint64} {
+			result, p := runParser(string(binary.AppendUvarint(nil, v))+"!", Uvarint())
+			require.Equal(t, v, result.Result)
+			require.Equal(t, "!", p.Get())
+		}
+	})
+
+	t.Run("varint", func(t *testing.T) {
+		for _, v := range []int64{0, -1, 1, -64, 64, math.MinInt64, math.MaxInt64} {
+			result, _ := runParser(string(binary.AppendVarint(nil, v)), Varint())
+			require.Equal(t, v, result.Result)
+		}
+	})
+
+	t.Run("leb128", func(t *testing.T) {
+		// examples from the LEB128 wikipedia page and the wasm spec
+		for input, expected := range map[string]int64{
+			"\x00":         0,
+			"\x02":         2,
+			"\x7e":         -2,
+			"\xff\x00":     127,
+			"\x81\x7f":     -127,
+			"\xc0\xbb\x78": -123456,
+			"\x80\x80\x80\x80\x80\x80\x80\x80\x80\x7f": math.MinInt64,
+			"\xff\xff\xff\xff\xff\xff\xff\xff\xff\x00": math.MaxInt64,
+		} {
+			result, p := runParser(input, LEB128())
+			require.False(t, p.Errored(), "%x", input)
+			require.Equal(t, expected, result.Result, "%x", input)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, p := runParser("\x80\x80", Uvarint())
+		require.Equal(t, "offset 0: expected uvarint", p.Error.Error())
+
+		_, p = runParser("\xff\xff\xff\xff\xff\xff\xff\xff\xff\x02", Uvarint())
+		require.Equal(t, "offset 0: expected uvarint", p.Error.Error())
+
+		_, p = runParser("\xff\xff\xff\xff\xff\xff\xff\xff\xff\x7f", Varint())
+		require.Equal(t, "offset 0: expected varint", p.Error.Error())
+
+		_, p = runParser("\x80\x80\x80\x80\x80\x80\x80\x80\x80\x7e", LEB128())
+		require.Equal(t, "offset 0: expected leb128", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+	})
+}
+
+func TestTake(t *testing.T) {
+	t.Run("length prefixed", func(t *testing.T) {
+		result, p := runParser("\x05hello world", Take(Byte()))
+		require.Equal(t, "hello", result.Token)
+		require.Equal(t, 1, result.Start)
+		require.Equal(t, " world", p.Get())
+
+		result, _ = runParser("\x00\x02hi", Take(Uint16(binary.BigEndian)))
+		require.Equal(t, "hi", result.Token)
+
+		result, _ = runParser("\x00", Take(Uvarint()))
+		require.Equal(t, "", result.Token)
+	})
+
+	t.Run("netstrings", func(t *testing.T) {
+		netstring := Seq(Take(Seq(NumberLitWith(NumberOptions{Unsigned: true, Strict: true}), ":").Map(func(n *Result) {
+			n.Result = n.Child[0].Result
+		})), ",")
+		result, err := Parse(netstring, "12:hello world!,")
+		require.NoError(t, err)
+		require.Equal(t, "hello world!", result.Child[0].Token)
+	})
+
+	t.Run("too short", func(t *testing.T) {
+		_, p := runParser("\x05abc", Take(Byte()))
+		require.Equal(t, "offset 1: expected 5 bytes", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+	})
+
+	t.Run("not a length", func(t *testing.T) {
+		_, p := runParser("abc", Take(Chars("a")))
+		require.Equal(t, "offset 1: expected <nil> bytes", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+
+		_, p = runParser("-1 abc", Take(NumberLit()))
+		require.Equal(t, "offset 2: expected -1 bytes", p.Error.Error())
+	})
+}
+
+func TestBinaryDoesntSkipWhitespace(t *testing.T) {
+	result, err := Run(Seq(Byte(), Uint16(binary.BigEndian), Take(Byte()), Uvarint()).Map(func(n *Result) {
+		n.Result = []interface{}{n.Child[0].Result, n.Child[1].Result, n.Child[2].Token, n.Child[3].Result}
+	}), " \x00\x20\x02 \t\x20")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{byte(' '), uint16(0x20), " \t", uint64(0x20)}, result)
+}
+
+func TestRunBytes(t *testing.T) {
+	tlv := OneOrMore(Seq(Byte(), Take(Uvarint())).Map(func(n *Result) {
+		n.Result = n.Child[1].Token
+	}))
+	input := []byte{1, 2, 'h', 'i', 2, 0, 3, 3, 'a', 'b', 'c'}
+
+	result, err := Parse(tlv, string(input))
+	require.NoError(t, err)
+	require.Equal(t, "abc", result.Child[2].Result)
+
+	_, err = RunBytes(tlv, input)
+	require.NoError(t, err)
+
+	runner := NewRunner(tlv, RunOptions{})
+	_, err = runner.RunBytes(input)
+	require.NoError(t, err)
+	_, err = runner.RunBytes(input[:len(input)-1])
+	require.Equal(t, "left unparsed: \x03\x03ab", err.Error())
+
+	// tokens point into the input rather than copying it
+	token := ""
+	_, err = RunBytes(Take(Byte()).Map(func(n *Result) { token = n.Token }), input[1:4])
+	require.NoError(t, err)
+	require.Equal(t, "hi", token)
+	require.Equal(t, &input[2], unsafe.StringData(token))
+}
@@ -70,5 +70,10 @@ func calc(input string) (float64, error) {
 		return 0, err
 	}
 
+	// y is optional, so an empty expression has no result
+	if result == nil {
+		return 0, nil
+	}
+
 	return result.(float64), nil
 }
@@ -1,8 +1,12 @@
 package calc
 
 import (
+	"errors"
+	"math/rand"
 	"testing"
 
+	"github.com/ajitid/goparsify"
+	"github.com/ajitid/goparsify/generate"
 	"github.com/stretchr/testify/require"
 )
 
@@ -52,3 +56,21 @@ func TestRecursive(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, 5.4, result)
 }
+
+func FuzzCalc(f *testing.F) {
+	gen := generate.New(y, generate.Options{MaxDepth: 10})
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		f.Add(gen.Generate(r))
+		f.Add(gen.GenerateInvalid(r))
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		_, err := calc(input)
+		var perr *goparsify.Error
+		if errors.As(err, &perr) {
+			require.True(t, perr.Pos() >= 0 && perr.Pos() <= len(input))
+			require.NotPanics(t, func() { perr.LocateError(input) })
+		}
+	})
+}
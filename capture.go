@@ -0,0 +1,115 @@
+package goparsify
+
+import "strings"
+
+// capture is a piece of input saved by Capture. Captures are a linked list, newest first, so backtracking can
+// go back to an earlier list without copying anything.
+type capture struct {
+	name string
+	text string
+	prev *capture
+}
+
+// Captured returns the text most recently captured as name by Capture, for custom parsers
+func (s *State) Captured(name string) (string, bool) {
+	for c := s.captures; c != nil; c = c.prev {
+		if c.name == name {
+			return c.text, true
+		}
+	}
+	return "", false
+}
+
+// Capture saves what parser matched as name, so Backref can match it again later on. It saves the .Token of the
+// result, or if it doesnt have one the input it matched. Captures made on paths that were backtracked out of are
+// forgotten, and a capture hides earlier ones with the same name.
+func Capture(name string, parser Parserish) Parser {
+	parserfied := Parsify(parser)
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarCapture, Name: "Capture(" + name + ")", Literal: name, parsers: []Parser{parserfied}}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		parserfied(ps, node)
+		if ps.Errored() {
+			return
+		}
+
+		text := node.Token
+		if text == "" {
+			text = ps.Input[node.Start:node.End]
+		}
+		ps.captures = &capture{name: name, text: text, prev: ps.captures}
+	})
+}
+
+// Backref matches the text last captured as name, eg the closing tag of a heredoc. It matches regardless of
+// case inside of FoldCase.
+func Backref(name string) Parser {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarBackref, Name: "Backref(" + name + ")", Literal: name}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		startpos := ps.Pos
+		ps.WS(ps)
+
+		text, ok := ps.Captured(name)
+		if !ok {
+			ps.ErrorHere("captured " + name)
+			ps.Pos = startpos
+			return
+		}
+
+		n := len(text)
+		if ps.FoldCase {
+			n = foldPrefix(ps.Get(), text)
+		} else if !strings.HasPrefix(ps.Get(), text) {
+			n = -1
+		}
+		if n < 0 {
+			ps.ErrorHere(text)
+			ps.Pos = startpos
+			return
+		}
+
+		node.Start = ps.Pos
+		node.End = ps.Pos + n
+		node.Token = ps.Input[node.Start:node.End]
+		ps.Advance(n)
+	})
+}
+
+// UntilBackref matches everything up to prefix followed by the text last captured as name, eg the body of a
+// heredoc is UntilBackref("tag", "\n"). Like Until whitespace isnt skipped, but it can match nothing, and
+// it is an error if the end is never found.
+func UntilBackref(name string, prefix string) Parser {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarUntilBackref, Name: "UntilBackref(" + name + ")", Literal: name}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		text, ok := ps.Captured(name)
+		if !ok {
+			ps.ErrorHere("captured " + name)
+			return
+		}
+
+		end := strings.Index(ps.Get(), prefix+text)
+		if end < 0 {
+			ps.Error = Error{pos: len(ps.Input), expected: prefix + text}
+			return
+		}
+
+		node.Start = ps.Pos
+		node.End = ps.Pos + end
+		node.Token = ps.Input[node.Start:node.End]
+		ps.Advance(end)
+	})
+}
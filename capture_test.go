@@ -0,0 +1,110 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture(t *testing.T) {
+	heredoc := Seq("<<", Capture("tag", Chars("A-Z")), NoAutoWS(Seq("\n", UntilBackref("tag", "\n"), "\n", Backref("tag"))))
+
+	t.Run("heredoc", func(t *testing.T) {
+		result, err := Parse(heredoc, "<<EOT\nline one\nEOF\nEOT")
+		require.NoError(t, err)
+		require.Equal(t, "EOT", result.Child[1].Token)
+		require.Equal(t, "line one\nEOF", result.Child[2].Child[1].Token)
+		require.Equal(t, "EOT", result.Child[2].Child[3].Token)
+
+		result, err = Parse(heredoc, "<<END\n\nEND")
+		require.NoError(t, err)
+		require.Equal(t, "", result.Child[2].Child[1].Token)
+	})
+
+	t.Run("unterminated heredoc", func(t *testing.T) {
+		_, err := Parse(heredoc, "<<EOT\nline one\nEO")
+		require.Equal(t, "offset 17: expected \nEOT", err.Error())
+	})
+
+	t.Run("rust raw strings", func(t *testing.T) {
+		raw := NoAutoWS(Seq("r", Capture("hashes", Chars("#", 0)), `"`, UntilBackref("hashes", `"`), `"`, Backref("hashes")))
+		result, err := Parse(raw, `r##"say "#hi"#"##`)
+		require.NoError(t, err)
+		require.Equal(t, `say "#hi"#`, result.Child[3].Token)
+
+		result, err = Parse(raw, `r"plain"`)
+		require.NoError(t, err)
+		require.Equal(t, "plain", result.Child[3].Token)
+	})
+
+	t.Run("captures input when there is no token", func(t *testing.T) {
+		fence := NoAutoWS(Seq(Capture("fence", Seq("`", "`", Chars("`", 1))), UntilBackref("fence", "\n"), "\n", Backref("fence")))
+		result, err := Parse(fence, "````\n```go\n```\n````")
+		require.NoError(t, err)
+		require.Equal(t, "\n```go\n```", result.Child[1].Token)
+	})
+
+	t.Run("missing capture", func(t *testing.T) {
+		_, p := runParser("abc", Backref("tag"))
+		require.Equal(t, "offset 0: expected captured tag", p.Error.Error())
+
+		_, p = runParser("abc", UntilBackref("tag", ""))
+		require.Equal(t, "offset 0: expected captured tag", p.Error.Error())
+	})
+
+	t.Run("mismatched backref", func(t *testing.T) {
+		tag := Seq("<", Capture("tag", Chars("a-z")), ">", Chars("a-z"), "</", Backref("tag"), ">")
+		_, err := Parse(tag, "<b>bold</i>")
+		require.Equal(t, "offset 9: expected b", err.Error())
+	})
+
+	t.Run("folds case", func(t *testing.T) {
+		result, err := Parse(FoldCase(Seq(Capture("word", Chars("a-zA-Z")), Backref("word"))), "Hey hEY")
+		require.NoError(t, err)
+		require.Equal(t, "hEY", result.Child[1].Token)
+	})
+
+	t.Run("the newest capture wins", func(t *testing.T) {
+		_, err := Parse(Seq(Capture("x", "a"), Capture("x", "b"), Backref("x")), "a b b")
+		require.NoError(t, err)
+	})
+}
+
+func TestCaptureBacktracking(t *testing.T) {
+	word := Capture("w", Chars("a-z"))
+
+	t.Run("any", func(t *testing.T) {
+		parser := Seq(Any(Seq(word, "!"), Chars("a-z")), Backref("w"))
+		_, p := runParser("abc abc", parser)
+		require.Equal(t, "offset 4: expected captured w", p.Error.Error())
+	})
+
+	t.Run("maybe", func(t *testing.T) {
+		parser := Seq(Capture("w", "x"), Maybe(Seq(word, "!")), Chars("a-z"), Backref("w"))
+		result, err := Parse(parser, "x abc x")
+		require.NoError(t, err)
+		require.Equal(t, "x", result.Child[3].Token)
+	})
+
+	t.Run("many", func(t *testing.T) {
+		parser := Seq(OneOrMore(Seq(word, ";")), Backref("w"))
+		_, p := runParser("a; b; c", parser)
+		require.Equal(t, "offset 6: expected b", p.Error.Error())
+
+	})
+
+	t.Run("seq", func(t *testing.T) {
+		parser := Seq(Any(Seq(word, "!"), Seq(Chars("a-z"), "?")), Backref("w"))
+		_, p := runParser("abc? abc", parser)
+		require.Equal(t, "offset 5: expected captured w", p.Error.Error())
+	})
+}
+
+func TestCaptureGrammar(t *testing.T) {
+	g := Describe(Seq(Capture("tag", Chars("A-Z")), UntilBackref("tag", "\n"), Backref("tag")))
+	require.Equal(t, GrammarCapture, g.Children()[0].Kind)
+	require.Equal(t, "tag", g.Children()[0].Literal)
+	require.Equal(t, GrammarChars, g.Children()[0].Children()[0].Kind)
+	require.Equal(t, GrammarUntilBackref, g.Children()[1].Kind)
+	require.Equal(t, GrammarBackref, g.Children()[2].Kind)
+}
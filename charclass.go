@@ -0,0 +1,187 @@
+package goparsify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// charClass is a compiled Chars matcher. ASCII is looked up in a bitset, anything else is binary searched in
+// a sorted table of ranges, then checked against the unicode classes.
+type charClass struct {
+	ascii   [2]uint64
+	ranges  []runeRange
+	classes []unicodeClass
+	// negated is set by a [^...] matcher, it inverts everything but the ascii bitset, which is built already inverted
+	negated bool
+}
+
+type runeRange struct {
+	lo, hi rune
+}
+
+// unicodeClass is a \p{Name} or \P{Name} in a matcher
+type unicodeClass struct {
+	table   *unicode.RangeTable
+	negated bool
+}
+
+func (u unicodeClass) contains(r rune) bool {
+	return unicode.Is(u.table, r) != u.negated
+}
+
+// lookupUnicodeClass finds a category, script or property by name, the same names regexp accepts in \p{Name}
+func lookupUnicodeClass(name string, negated bool) unicodeClass {
+	for _, tables := range []map[string]*unicode.RangeTable{unicode.Categories, unicode.Scripts, unicode.Properties} {
+		if table := tables[name]; table != nil {
+			return unicodeClass{table: table, negated: negated}
+		}
+	}
+	panic(fmt.Errorf("unknown unicode class %q", name))
+}
+
+// newCharClass compiles a matcher in the format a-f01234A-F\p{L}, or [^a-f01234A-F\p{L}] to negate it, see Chars
+// and parseMatcher
+func newCharClass(matcher string) *charClass {
+	c := &charClass{}
+	if negated, ok := negatedMatcher(matcher); ok {
+		c.negated = true
+		matcher = negated
+	}
+
+	alphabet, ranges, classes := parseMatcher(matcher)
+	c.classes = classes
+
+	var all []runeRange
+	for _, r := range alphabet {
+		all = append(all, runeRange{r, r})
+	}
+	for _, rng := range ranges {
+		all = append(all, runeRange{rng[0], rng[1]})
+	}
+
+	for _, rng := range all {
+		for r := rng.lo; r <= rng.hi && r < utf8.RuneSelf; r++ {
+			c.ascii[r>>6] |= 1 << uint(r&63)
+		}
+		if rng.hi >= utf8.RuneSelf {
+			if rng.lo < utf8.RuneSelf {
+				rng.lo = utf8.RuneSelf
+			}
+			c.ranges = append(c.ranges, rng)
+		}
+	}
+
+	// sort and merge overlapping ranges, so contains can binary search them
+	sort.Slice(c.ranges, func(i, j int) bool { return c.ranges[i].lo < c.ranges[j].lo })
+	merged := c.ranges[:0]
+	for _, rng := range c.ranges {
+		if n := len(merged); n > 0 && rng.lo <= merged[n-1].hi+1 {
+			if rng.hi > merged[n-1].hi {
+				merged[n-1].hi = rng.hi
+			}
+			continue
+		}
+		merged = append(merged, rng)
+	}
+	c.ranges = merged
+
+	for r := rune(0); r < utf8.RuneSelf; r++ {
+		in := c.containsByte(byte(r))
+		for _, class := range c.classes {
+			in = in || class.contains(r)
+		}
+		if in != c.negated {
+			c.ascii[r>>6] |= 1 << uint(r&63)
+		} else {
+			c.ascii[r>>6] &^= 1 << uint(r&63)
+		}
+	}
+
+	return c
+}
+
+// negatedMatcher returns what is inside of a [^...] matcher, as long as the closing ] isnt escaped
+func negatedMatcher(matcher string) (string, bool) {
+	if len(matcher) < 4 || !strings.HasPrefix(matcher, "[^") || !strings.HasSuffix(matcher, "]") {
+		return "", false
+	}
+	inside := matcher[2 : len(matcher)-1]
+	escapes := len(inside) - len(strings.TrimRight(inside, "\\"))
+	if escapes%2 == 1 {
+		return "", false
+	}
+	return inside, true
+}
+
+// containsByte reports whether the ASCII character b is in the class, b must be less than utf8.RuneSelf
+func (c *charClass) containsByte(b byte) bool {
+	return c.ascii[b>>6]&(1<<(b&63)) != 0
+}
+
+func (c *charClass) contains(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r >= 0 && c.containsByte(byte(r))
+	}
+	if r > unicode.MaxRune {
+		return false
+	}
+
+	i := sort.Search(len(c.ranges), func(i int) bool { return c.ranges[i].hi >= r })
+	in := i < len(c.ranges) && c.ranges[i].lo <= r
+	for _, class := range c.classes {
+		if in {
+			break
+		}
+		in = class.contains(r)
+	}
+	return in != c.negated
+}
+
+// candidates returns sorted, non overlapping ranges that hold every rune in the class, or nil when it is most of unicode
+func (c *charClass) candidates() [][2]rune {
+	if c.negated {
+		return nil
+	}
+
+	var ranges [][2]rune
+	for r := rune(0); r < utf8.RuneSelf; r++ {
+		if !c.containsByte(byte(r)) {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1][1] == r-1 {
+			ranges[n-1][1] = r
+		} else {
+			ranges = append(ranges, [2]rune{r, r})
+		}
+	}
+	for _, rng := range c.ranges {
+		ranges = append(ranges, [2]rune{rng.lo, rng.hi})
+	}
+	for _, class := range c.classes {
+		if class.negated {
+			return nil
+		}
+		for _, rng := range class.table.R16 {
+			ranges = append(ranges, [2]rune{rune(rng.Lo), rune(rng.Hi)})
+		}
+		for _, rng := range class.table.R32 {
+			ranges = append(ranges, [2]rune{rune(rng.Lo), rune(rng.Hi)})
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := ranges[:0]
+	for _, rng := range ranges {
+		if n := len(merged); n > 0 && rng[0] <= merged[n-1][1]+1 {
+			if rng[1] > merged[n-1][1] {
+				merged[n-1][1] = rng[1]
+			}
+			continue
+		}
+		merged = append(merged, rng)
+	}
+	return merged
+}
@@ -0,0 +1,128 @@
+package goparsify
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCharClass(t *testing.T) {
+	t.Run("alphabet and ranges", func(t *testing.T) {
+		c := newCharClass(`a-cx\-z-wé`)
+		for _, r := range "abcx-wyzé" {
+			require.True(t, c.contains(r), string(r))
+		}
+		for _, r := range "dA\\vè" {
+			require.False(t, c.contains(r), string(r))
+		}
+	})
+
+	t.Run("merges unicode ranges", func(t *testing.T) {
+		c := newCharClass("α-γβ-εζ~-é")
+		require.Equal(t, []runeRange{{0x80, 'é'}, {'α', 'ζ'}}, c.ranges)
+		require.True(t, c.contains('~'))
+		require.True(t, c.contains(0x80))
+		require.False(t, c.contains('η'))
+	})
+
+	t.Run("rejects runes that arent characters", func(t *testing.T) {
+		c := newCharClass("a-z")
+		require.False(t, c.contains(-1))
+		require.False(t, c.contains(utf8.RuneError))
+		require.False(t, c.contains(utf8.MaxRune+1))
+	})
+
+	t.Run("unicode classes", func(t *testing.T) {
+		c := newCharClass(`\p{L}\p{Nd}_`)
+		for _, r := range "aZéßΩж日_7٣" {
+			require.True(t, c.contains(r), string(r))
+		}
+		for _, r := range "-. ½€" {
+			require.False(t, c.contains(r), string(r))
+		}
+
+		greek := newCharClass(`\p{Greek}`)
+		require.True(t, greek.contains('λ'))
+		require.False(t, greek.contains('l'))
+
+		notGreek := newCharClass(`\P{Greek}`)
+		require.False(t, notGreek.contains('λ'))
+		require.True(t, notGreek.contains('l'))
+		require.True(t, notGreek.contains('日'))
+	})
+
+	t.Run("negation", func(t *testing.T) {
+		c := newCharClass(`[^a-z\p{Greek}]`)
+		require.False(t, c.contains('q'))
+		require.False(t, c.contains('λ'))
+		require.True(t, c.contains('Q'))
+		require.True(t, c.contains('日'))
+		require.False(t, c.contains(-1))
+
+		// only [^...] negates, a ^ on its own is just a character
+		require.True(t, newCharClass("^").contains('^'))
+		require.True(t, newCharClass("^_").contains('^'))
+		require.True(t, newCharClass("^_").contains('_'))
+		require.False(t, newCharClass("^_").contains('a'))
+		require.True(t, newCharClass("[^]").contains('^'))
+		require.True(t, newCharClass(`[^a\]`).contains(']'))
+		require.False(t, newCharClass(`[^a\\]`).contains('a'))
+	})
+
+	t.Run("panics on bad classes", func(t *testing.T) {
+		require.PanicsWithError(t, `unknown unicode class "Klingon"`, func() { newCharClass(`\p{Klingon}`) })
+		require.PanicsWithError(t, `unterminated unicode class in "\\p{L"`, func() { newCharClass(`\p{L`) })
+	})
+
+	t.Run("matches the uncompiled matcher", func(t *testing.T) {
+		reference := func(matcher string, r rune) bool {
+			inside := strings.TrimSuffix(strings.TrimPrefix(matcher, "[^"), "]")
+			escaped := (len(inside) - len(strings.TrimRight(inside, `\`))) % 2
+			negated := len(matcher) >= 4 && len(inside) == len(matcher)-3 && escaped == 0
+			if negated {
+				matcher = inside
+			}
+			alphabet, ranges, classes := parseMatcher(matcher)
+			if strings.ContainsRune(alphabet, r) {
+				return !negated
+			}
+			for _, rng := range ranges {
+				if r >= rng[0] && r <= rng[1] {
+					return !negated
+				}
+			}
+			for _, class := range classes {
+				if unicode.Is(class.table, r) != class.negated {
+					return !negated
+				}
+			}
+			return negated
+		}
+
+		pool := []rune("abcxyz09-\\^[]é日αω\u007f\u0080")
+		classes := []string{`\p{L}`, `\P{L}`, `\p{Nd}`, `\p{Greek}`, `\P{Han}`, `\p{White_Space}`}
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 2000; i++ {
+			matcher := &strings.Builder{}
+			for j := r.Intn(8); j > 0; j-- {
+				if r.Intn(6) == 0 {
+					matcher.WriteString(classes[r.Intn(len(classes))])
+				} else {
+					matcher.WriteRune(pool[r.Intn(len(pool))])
+				}
+			}
+			m := matcher.String()
+			if r.Intn(3) == 0 {
+				m = "[^" + m + "]"
+			}
+			c := newCharClass(m)
+			for _, ch := range pool {
+				require.Equal(t, reference(m, ch), c.contains(ch), "%q contains %q", m, ch)
+			}
+		}
+	})
+}
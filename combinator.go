@@ -3,20 +3,28 @@ package goparsify
 import (
 	"bytes"
 	"strings"
+	"sync"
 )
 
 // Seq matches all of the given parsers in order and returns their result as .Child[n]
 func Seq(parsers ...Parserish) Parser {
 	parserfied := ParsifyAll(parsers...)
 
-	return NewParser("Seq()", func(ps *State, node *Result) {
-		node.Child = make([]Result, len(parserfied))
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarSeq, Name: "Seq()", parsers: parserfied}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		node.Child = ps.arena.alloc(len(parserfied), node.Input)
 		startpos := ps.Pos
+		captures, user := ps.captures, ps.User
 		for i, parser := range parserfied {
-			node.Child[i].Input = node.Input
 			parser(ps, &node.Child[i])
 			if ps.Errored() {
 				ps.Pos = startpos
+				ps.captures, ps.User = captures, user
 				return
 			}
 		}
@@ -28,7 +36,13 @@ func Seq(parsers ...Parserish) Parser {
 // NoAutoWS disables automatically ignoring whitespace between tokens for all parsers underneath
 func NoAutoWS(parser Parserish) Parser {
 	parserfied := Parsify(parser)
-	return func(ps *State, node *Result) {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarNoAutoWS, Name: "NoAutoWS()", parsers: []Parser{parserfied}}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
 		oldWS := ps.WS
 		ps.WS = NoWhitespace
 		startpos := ps.Pos
@@ -36,38 +50,109 @@ func NoAutoWS(parser Parserish) Parser {
 		node.Start = startpos
 		node.End = ps.Pos
 		ps.WS = oldWS
-	}
+	})
 }
 
-// Any matches the first successful parser and returns its result
+// FoldCase makes every Exact and string in parser match regardless of case, using unicode simple case folding.
+// Wrap the root of a grammar in it for languages like SQL. .Token is the input as written, not the string
+// being matched. Chars, Regex and OneOf keep their own rules, use a-zA-Z, (?i) or OneOfOptions.FoldCase.
+func FoldCase(parser Parserish) Parser {
+	parserfied := Parsify(parser)
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarFoldCase, Name: "FoldCase()", parsers: []Parser{parserfied}}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		oldFold := ps.FoldCase
+		ps.FoldCase = true
+		parserfied(ps, node)
+		ps.FoldCase = oldFold
+	})
+}
+
+// Any matches the first successful parser and returns its result.
+//
+// Alternatives that can only start with certain bytes, like Exact, Chars, StringLit and NumberLit, are skipped
+// without being run when the next byte rules them out. This relies on every alternative skipping whitespace the
+// same way as the parsers around them. It is turned off while tracing, so traces show every attempt.
 func Any(parsers ...Parserish) Parser {
 	parserfied := ParsifyAll(parsers...)
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarAny, Name: "Any()", parsers: parserfied}
+	})
 
-	return NewParser("Any()", func(ps *State, node *Result) {
+	// alternatives may be pointers that are only set in init, so wait for the first parse to look at them.
+	// Theres a table for inside of FoldCase too, where Exact can start with either case.
+	var dispatchOnce [2]sync.Once
+	var dispatchTables [2]*dispatchTable
+
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		wspos := ps.Pos
 		ps.WS(ps)
 		if ps.Pos >= len(ps.Input) {
 			ps.ErrorHere("!EOF")
+			ps.Pos = wspos
 			return
 		}
 		startpos := ps.Pos
+		mark := ps.mark()
+		cut := ps.Cut
+
+		fold := 0
+		if ps.FoldCase {
+			fold = 1
+		}
+		dispatchOnce[fold].Do(func() { dispatchTables[fold] = newDispatchTable(g.grammar().Children(), fold == 1) })
+		dispatch := dispatchTables[fold]
+		viable := ^uint64(0)
+		if dispatch != nil && ps.Tracer == nil {
+			viable = dispatch.viable[ps.Input[startpos]]
+		}
 
 		var longestError Error
-		expected := []string{}
-		for _, parser := range parserfied {
+		// most choices have a few alternatives, so only ones with more need to allocate for the error
+		var expectedBuf [8]string
+		expected := expectedBuf[:0]
+		for i, parser := range parserfied {
+			if viable&(1<<uint(i)) == 0 {
+				// this is exactly the error running it would have given
+				if startpos >= longestError.pos {
+					longestError = Error{pos: startpos, expected: dispatch.expected[i]}
+					expected = append(expected, longestError.expected)
+				}
+				// and counts the same, so limits dont change when tracing
+				if ps.maxErrors > 0 {
+					ps.recovered(startpos)
+				}
+				continue
+			}
+
 			parser(ps, node)
 			if ps.Errored() {
 				if ps.Error.pos >= longestError.pos {
 					longestError = ps.Error
 					expected = append(expected, ps.Error.expected)
 				}
-				if ps.Cut > startpos {
+				if ps.cutAfter(startpos) {
 					break
 				}
 				ps.Recover()
+				// dont leave anything from a failed branch behind for the next one
+				*node = Result{Input: node.Input}
+				ps.reset(mark)
 				continue
 			}
 			node.Start = startpos
 			node.End = ps.Pos
+			ps.Cut = cut
+			if ps.Tracer != nil {
+				g.grammar().matchedBranch(i)
+			}
 			return
 		}
 
@@ -75,70 +160,161 @@ func Any(parsers ...Parserish) Parser {
 			pos:      longestError.pos,
 			expected: strings.Join(expected, " or "),
 		}
-		ps.Pos = startpos
+		ps.Pos = wspos
+		ps.Cut = cut
 	})
 }
 
 // ZeroOrMore matches zero or more parsers and returns the value as .Child[n]
 // an optional separator can be provided and that value will be consumed
-// but not returned. Only one separator can be provided.
+// but not returned. Only one separator can be provided. A separator after
+// the last item is consumed too, use SepBy to leave it.
 func ZeroOrMore(parser Parserish, separator ...Parserish) Parser {
-	return NewParser("ZeroOrMore()", manyImpl(0, parser, separator...))
+	return manyImpl("ZeroOrMore()", 0, parser, separator...)
 }
 
 // OneOrMore matches one or more parsers and returns the value as .Child[n]
 // an optional separator can be provided and that value will be consumed
-// but not returned. Only one separator can be provided.
+// but not returned. Only one separator can be provided. A separator after
+// the last item is consumed too, use SepBy1 to leave it.
 func OneOrMore(parser Parserish, separator ...Parserish) Parser {
-	return NewParser("OneOrMore()", manyImpl(1, parser, separator...))
+	return manyImpl("OneOrMore()", 1, parser, separator...)
+}
+
+func manyImpl(name string, min int, op Parserish, sep ...Parserish) Parser {
+	opts := RepeatOptions{Min: min, Max: -1, Trailing: TrailingOptional}
+	if len(sep) > 0 {
+		opts.Separator = sep[0]
+	}
+	return repeatImpl(name, op, opts)
 }
 
-func manyImpl(min int, op Parserish, sep ...Parserish) Parser {
+// repeatImpl is every repetition, opts.Max < 0 is no limit
+func repeatImpl(name string, op Parserish, opts RepeatOptions) Parser {
 	var opParser = Parsify(op)
 	var sepParser Parser
-	if len(sep) > 0 {
-		sepParser = Parsify(sep[0])
+	if opts.Separator != nil {
+		sepParser = Parsify(opts.Separator)
 	}
+	min, max, trailing, keep := opts.Min, opts.Max, opts.Trailing, opts.KeepSeparators
 
-	return func(ps *State, node *Result) {
-		node.Child = make([]Result, 0, 5)
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarMany, Name: name, Min: min, Max: max, Trailing: trailing, parsers: []Parser{opParser}, separator: sepParser}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		node.Child = ps.arena.alloc(5, node.Input)[:0]
 		startpos := ps.Pos
-		for {
-			node.Child = append(node.Child, Result{Input: node.Input})
+		captures, user := ps.captures, ps.User
+		// each item is a choice between matching it and stopping, so cuts only last for an item
+		cut := ps.Cut
+		// the separator before the item being matched, which TrailingNever gives back if the item isnt there
+		sepMark, seppos, sepChildren := stateMark{}, -1, 0
+		// where the separator itself starts, after any whitespace
+		sepStart := 0
+		for n := 0; max < 0 || n < max; n++ {
+			// room for the item and its separator
+			if len(node.Child)+2 > cap(node.Child) {
+				grown := ps.arena.alloc(2*cap(node.Child), node.Input)
+				copy(grown, node.Child)
+				node.Child = grown[:len(node.Child)]
+			}
+			itempos := ps.Pos
+			mark := ps.mark()
+			node.Child = node.Child[:len(node.Child)+1]
 			opParser(ps, &node.Child[len(node.Child)-1])
 			if ps.Errored() {
-				if len(node.Child)-1 < min || ps.Cut > ps.Pos {
+				if n < min || ps.cutAfter(itempos) {
 					ps.Pos = startpos
+					ps.captures, ps.User = captures, user
+					ps.Cut = cut
 					return
 				}
 				ps.Recover()
+				ps.reset(mark)
 				node.Child = node.Child[0 : len(node.Child)-1]
-				return
+				if seppos >= 0 && trailing == TrailingNever {
+					// node.Child may have grown since the separator, so its results are left in the arena
+					ps.Pos = seppos
+					ps.captures, ps.User = sepMark.captures, sepMark.user
+					node.Child = node.Child[:sepChildren]
+					ps.trailingSep = sepStart + 1
+				}
+				break
 			}
 
-			if sepParser != nil {
-				sepParser(ps, TrashResult)
+			seppos = -1
+			if sepParser != nil && !(trailing == TrailingNever && n+1 == max) {
+				sepMark, seppos, sepChildren = ps.mark(), ps.Pos, len(node.Child)
+				// separators that arent returned still need a result of their own to write to,
+				// sharing TrashResult would be a race between goroutines
+				if keep {
+					node.Child = node.Child[:len(node.Child)+1]
+					sepParser(ps, &node.Child[len(node.Child)-1])
+					sepStart = node.Child[len(node.Child)-1].Start
+				} else {
+					sep := &ps.arena.alloc(1, node.Input)[0]
+					sepParser(ps, sep)
+					sepStart = sep.Start
+					ps.arena.reset(sepMark.arena)
+				}
 				if ps.Errored() {
+					if trailing == TrailingRequired {
+						// an item has to have a separator after it
+						if n < min || ps.cutAfter(itempos) {
+							ps.Pos = startpos
+							ps.captures, ps.User = captures, user
+							ps.Cut = cut
+							return
+						}
+						ps.Recover()
+						ps.reset(mark)
+						ps.Pos = itempos
+						node.Child = node.Child[:sepChildren-1]
+						break
+					}
 					ps.Recover()
-					return
+					ps.reset(sepMark)
+					node.Child = node.Child[:sepChildren]
+					break
 				}
 			}
+			ps.Cut = cut
+
+			// an item that matches nothing would match nothing forever
+			if ps.Pos == itempos && max < 0 && n+1 >= min {
+				break
+			}
 		}
+		ps.Cut = cut
 		node.Start = startpos
 		node.End = ps.Pos
-	}
+	})
 }
 
 // Maybe will 0 or 1 of the parser
 func Maybe(parser Parserish) Parser {
 	parserfied := Parsify(parser)
 
-	return NewParser("Maybe()", func(ps *State, node *Result) {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarMaybe, Name: "Maybe()", parsers: []Parser{parserfied}}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
 		startpos := ps.Pos
+		mark := ps.mark()
+		cut := ps.Cut
 		parserfied(ps, node)
-		if ps.Errored() && ps.Cut <= startpos {
+		if ps.Errored() && !ps.cutAfter(startpos) {
 			ps.Recover()
+			*node = Result{Input: node.Input}
+			ps.reset(mark)
 		}
+		ps.Cut = cut
 		node.Start = startpos
 		node.End = ps.Pos
 	})
@@ -150,7 +326,7 @@ func Maybe(parser Parserish) Parser {
 func Bind(parser Parserish, val interface{}) Parser {
 	p := Parsify(parser)
 
-	return func(ps *State, node *Result) {
+	return wrapParser(p, func(ps *State, node *Result) {
 		startpos := ps.Pos
 		p(ps, node)
 		if ps.Errored() {
@@ -159,7 +335,7 @@ func Bind(parser Parserish, val interface{}) Parser {
 		node.Result = val
 		node.Start = startpos
 		node.End = ps.Pos
-	}
+	})
 }
 
 // Map applies the callback if the parser matches. This is used to set the Result
@@ -167,7 +343,7 @@ func Bind(parser Parserish, val interface{}) Parser {
 func Map(parser Parserish, f func(n *Result)) Parser {
 	p := Parsify(parser)
 
-	return func(ps *State, node *Result) {
+	return wrapParser(p, func(ps *State, node *Result) {
 		startpos := ps.Pos
 		p(ps, node)
 		if ps.Errored() {
@@ -176,7 +352,23 @@ func Map(parser Parserish, f func(n *Result)) Parser {
 		node.Start = startpos
 		node.End = ps.Pos
 		f(node)
-	}
+	})
+}
+
+// MapState is Map for callbacks that need the State too, eg to read or set State.User
+func MapState(parser Parserish, f func(ps *State, n *Result)) Parser {
+	p := Parsify(parser)
+
+	return wrapParser(p, func(ps *State, node *Result) {
+		startpos := ps.Pos
+		p(ps, node)
+		if ps.Errored() {
+			return
+		}
+		node.Start = startpos
+		node.End = ps.Pos
+		f(ps, node)
+	})
 }
 
 // Chain lets you choose which parser to call on the basis of the result of
@@ -187,10 +379,21 @@ func Map(parser Parserish, f func(n *Result)) Parser {
 //
 // Result of this successive parser is considered as the result of the Chain.
 func Chain(parser Parserish, getNextParser func(prevN *Result) Parserish) Parser {
+	return ChainState(parser, func(ps *State, prevN *Result) Parserish { return getNextParser(prevN) })
+}
+
+// ChainState is Chain for callbacks that need the State too, eg to pick the next parser based on State.User
+func ChainState(parser Parserish, getNextParser func(ps *State, prevN *Result) Parserish) Parser {
 	p1 := Parsify(parser)
 
-	return func(ps *State, node *Result) {
+	// the parser that follows is only known while parsing, so there is nothing useful to describe
+	g := opaqueGrammar("Chain()")
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
 		startpos := ps.Pos
+		captures, user := ps.captures, ps.User
 
 		r1 := NewResult(node.Input)
 		p1(ps, r1)
@@ -199,15 +402,17 @@ func Chain(parser Parserish, getNextParser func(prevN *Result) Parserish) Parser
 			return
 		}
 
-		p2 := Parsify(getNextParser(r1))
+		p2 := Parsify(getNextParser(ps, r1))
 		p2(ps, node)
 		if ps.Errored() {
+			ps.Pos = startpos
+			ps.captures, ps.User = captures, user
 			return
 		}
 
 		node.Start = startpos
 		node.End = ps.Pos
-	}
+	})
 }
 
 func flatten(n *Result) {
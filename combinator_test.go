@@ -33,6 +33,12 @@ func TestMaybe(t *testing.T) {
 		require.Equal(t, " world", p2.Get())
 	})
 
+	t.Run("doesnt keep partial results", func(t *testing.T) {
+		node, ps := runParser("hello there", Maybe(Seq("hello", "world")))
+		require.Equal(t, Result{}, node)
+		require.False(t, ps.Errored())
+	})
+
 	t.Run("returns no errors", func(t *testing.T) {
 		node, p3 := runParser("hello world", Maybe("world"))
 		require.Equal(t, Result{}, node)
@@ -65,6 +71,12 @@ func TestAny(t *testing.T) {
 		require.Equal(t, 0, p2.Pos)
 	})
 
+	t.Run("Doesnt keep results of failed branches", func(t *testing.T) {
+		node, _ := runParser("hello", Any(Seq("hello", "world"), "hello"))
+		require.Equal(t, "hello", node.Token)
+		require.Nil(t, node.Child)
+	})
+
 	t.Run("overlapping longest match", func(t *testing.T) {
 		EnableLogging(os.Stdout)
 		p := OneOrMore(Any("ab", "a"))
@@ -126,6 +138,14 @@ func TestZeroOrMore(t *testing.T) {
 		require.Equal(t, "", p2.Get())
 	})
 
+	t.Run("Stops when nothing is consumed", func(t *testing.T) {
+		node, p2 := runParser("aab", ZeroOrMore(Maybe("a")))
+		assertSequence(t, node, "a", "a", "")
+		require.Equal(t, "b", p2.Get())
+		require.Equal(t, 0, node.Start)
+		require.Equal(t, 2, node.End)
+	})
+
 	t.Run("Stops on error", func(t *testing.T) {
 		node, p2 := runParser("a,b,c,d,e,", ZeroOrMore(Chars("a-c"), ","))
 		assertSequence(t, node, "a", "b", "c")
@@ -235,7 +255,7 @@ func TestChain(t *testing.T) {
 	t.Run("error", func(t *testing.T) {
 		_, ps := runParser("number:&*%", parser)
 		require.Equal(t, "offset 7: expected number", ps.Error.Error())
-		require.Equal(t, 7, ps.Pos)
+		require.Equal(t, 0, ps.Pos)
 	})
 
 	t.Run("wrong type", func(t *testing.T) {
@@ -270,8 +290,8 @@ func TestCut(t *testing.T) {
 	})
 
 	t.Run("test one or more", func(t *testing.T) {
-		_, ps := runParser("hello <world", OneOrMore(Any(Seq("<", Cut(), Chars("a-z"), ">"), Chars("a-z"))))
-		require.Equal(t, "offset 12: expected >", ps.Error.Error())
+		_, ps := runParser("<hello> <world", OneOrMore(Seq("<", Cut(), Chars("a-z"), ">")))
+		require.Equal(t, "offset 14: expected >", ps.Error.Error())
 		require.Equal(t, 0, ps.Pos)
 	})
 
@@ -280,6 +300,66 @@ func TestCut(t *testing.T) {
 		require.Equal(t, "offset 3: expected hello", ps.Error.Error())
 		require.Equal(t, 0, ps.Pos)
 	})
+
+	t.Run("only commits the nearest choice", func(t *testing.T) {
+		// the cut commits the inner Any, which then fails like it would without one
+		result, ps := runParser("hello <world", OneOrMore(Any(Seq("<", Cut(), Chars("a-z"), ">"), Chars("a-z"))))
+		require.False(t, ps.Errored())
+		require.Len(t, result.Child, 1)
+		require.Equal(t, " <world", ps.Get())
+
+		_, ps = runParser("abd", Any(Seq(Any(Seq("a", Cut(), "b")), "c"), Seq("a", "b", "d")))
+		require.False(t, ps.Errored())
+		require.Equal(t, "", ps.Get())
+
+		_, ps = runParser("abd", Any(Seq(Maybe(Seq("a", Cut(), "b")), "c"), "abd"))
+		require.False(t, ps.Errored())
+
+		_, ps = runParser("abd", Any(Seq(ZeroOrMore(Seq("a", Cut(), "b")), "c"), "abd"))
+		require.False(t, ps.Errored())
+	})
+
+	t.Run("doesnt outlive the choice", func(t *testing.T) {
+		// a cut that led to a match must not stop a later choice from backtracking
+		parser := Seq(Any(Seq("a", Cut(), "b"), "c"), Any(Seq("x", "y"), "xz"))
+		_, ps := runParser("abxz", parser)
+		require.False(t, ps.Errored())
+		require.Equal(t, "", ps.Get())
+
+		parser = Seq(Maybe(Seq("a", Cut(), "b")), Any(Seq("x", "y"), "xz"))
+		_, ps = runParser("abxz", parser)
+		require.False(t, ps.Errored())
+
+		parser = Seq(ZeroOrMore(Seq("a", Cut(), "b")), Any(Seq("x", "y"), "xz"))
+		_, ps = runParser("ababxz", parser)
+		require.False(t, ps.Errored())
+		require.Equal(t, 0, ps.Cut)
+	})
+
+	t.Run("inside of many items", func(t *testing.T) {
+		// every item is committed separately
+		result, ps := runParser("a1 a2 b3", ZeroOrMore(Any(Seq("a", Cut(), Chars("0-9")), Seq(Chars("a-z"), Chars("0-9")))))
+		require.False(t, ps.Errored())
+		require.Len(t, result.Child, 3)
+
+		_, ps = runParser("a1,ax", ZeroOrMore(Seq("a", Cut(), Chars("0-9")), ","))
+		require.Equal(t, "offset 4: expected 0-9", ps.Error.Error())
+		require.Equal(t, 0, ps.Pos)
+
+		_, ps = runParser("ax", Seq(Maybe(Seq("a", Cut(), Chars("0-9"))), Chars("a-z")))
+		require.Equal(t, "offset 1: expected 0-9", ps.Error.Error())
+	})
+
+	t.Run("reports the committed error", func(t *testing.T) {
+		_, err := Run(OneOrMore(Any(Seq("<", Cut(), Chars("a-z"), ">"), Chars("a-z"))), "hello <world")
+		require.Equal(t, "offset 12: expected >", err.Error())
+
+		_, err = Run(Seq(Any(Seq("(", Cut(), Chars("a-z"), ")"), "("), "!"), "(ab!")
+		require.Equal(t, "offset 3: expected )", err.Error())
+
+		_, err = Run(Any(Seq("<", Cut(), Chars("a-z"), ">"), Chars("a-z")), "hello")
+		require.NoError(t, err)
+	})
 }
 
 func TestMerge(t *testing.T) {
@@ -0,0 +1,312 @@
+package goparsify
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// covering is only set between EnableCoverage and DisableCoverage
+var covering atomic.Bool
+
+// coverageTracer is the Tracer of States made while coverage is enabled, it only counts calls and matches
+var coverageTracer = &Tracer{}
+
+// EnableCoverage starts counting how often every parser created with NewParser, and every branch of every Any,
+// matches. It is intended to be called from TestMain to find the parts of a grammar the tests never exercise:
+//
+//	func TestMain(m *testing.M) {
+//		goparsify.EnableCoverage()
+//		code := m.Run()
+//		_ = goparsify.GetCoverage().WriteText(os.Stdout)
+//		os.Exit(code)
+//	}
+//
+// Grammars are reported from the first time they are run, along with every parser underneath them. Parses that
+// are given their own Tracer are counted too. Counting is global and safe for concurrent parses, but it is not free so dont leave it on in production.
+func EnableCoverage() {
+	covering.Store(true)
+}
+
+// DisableCoverage stops counting, the counts collected so far are kept
+func DisableCoverage() {
+	covering.Store(false)
+}
+
+// ResetCoverage sets all the coverage counts back to zero
+func ResetCoverage() {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, info := range registry.order {
+		atomic.StoreInt64(&info.calls, 0)
+		atomic.StoreInt64(&info.matches, 0)
+		for i := range info.branches {
+			atomic.StoreInt64(&info.branches[i], 0)
+		}
+	}
+}
+
+// RuleCoverage is how often a single parser was used while coverage was enabled
+type RuleCoverage struct {
+	// Var is the name of the variable the parser was assigned to, if it could be found
+	Var string
+	// Match is the description given to NewParser, eg "Seq()" or the literal being matched
+	Match string
+	// File and Line are where the parser was defined
+	File string
+	Line int
+	// Calls is the number of times the parser was invoked
+	Calls int
+	// Matches is the number of times the parser succeeded
+	Matches int
+	// Branches holds the number of times each alternative of an Any matched, it is nil for other parsers
+	Branches []int
+}
+
+// Location is the short file:line form of where the parser was defined
+func (rc RuleCoverage) Location() string {
+	if rc.File == "" {
+		return "?"
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(rc.File), rc.Line)
+}
+
+// name is the variable the parser was assigned to, or what it matches when that isnt known
+func (rc RuleCoverage) name() string {
+	if rc.Var == "" {
+		return rc.Match
+	}
+	return rc.Var
+}
+
+// Covered is true when the parser and all of its branches have matched at least once
+func (rc RuleCoverage) Covered() bool {
+	if rc.Matches == 0 {
+		return false
+	}
+	for _, n := range rc.Branches {
+		if n == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Coverage is a snapshot of the coverage counts for every parser, ordered by where they were defined
+type Coverage []RuleCoverage
+
+// GetCoverage takes a snapshot of the current coverage counts
+func GetCoverage() Coverage {
+	registry.Lock()
+	infos := append([]*parserInfo{}, registry.order...)
+	registry.Unlock()
+
+	ret := make(Coverage, 0, len(infos))
+	for _, info := range infos {
+		site := info.definition()
+		rc := RuleCoverage{
+			Var:     site.Var,
+			Match:   info.match,
+			File:    site.File,
+			Line:    site.Line,
+			Calls:   int(atomic.LoadInt64(&info.calls)),
+			Matches: int(atomic.LoadInt64(&info.matches)),
+		}
+		if info.branches != nil {
+			rc.Branches = make([]int, len(info.branches))
+			for i := range info.branches {
+				rc.Branches[i] = int(atomic.LoadInt64(&info.branches[i]))
+			}
+		}
+		ret = append(ret, rc)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].File != ret[j].File {
+			return ret[i].File < ret[j].File
+		}
+		if ret[i].Line != ret[j].Line {
+			return ret[i].Line < ret[j].Line
+		}
+		// parsers without a definition are ordered by name
+		return ret[i].File == "" && ret[i].name() < ret[j].name()
+	})
+	return ret
+}
+
+// Filter returns the rules for which keep returns true, eg to only report on a single grammar file
+func (c Coverage) Filter(keep func(rc RuleCoverage) bool) Coverage {
+	ret := Coverage{}
+	for _, rc := range c {
+		if keep(rc) {
+			ret = append(ret, rc)
+		}
+	}
+	return ret
+}
+
+// Uncovered returns the rules that never matched, or that have an Any branch that never matched
+func (c Coverage) Uncovered() Coverage {
+	return c.Filter(func(rc RuleCoverage) bool { return !rc.Covered() })
+}
+
+// WriteText writes a plain text report with a line per parser, and a line per branch of each Any
+func (c Coverage) WriteText(w io.Writer) error {
+	buf := &strings.Builder{}
+	rules, covered, branches, coveredBranches := 0, 0, 0, 0
+
+	for _, rc := range c {
+		rules++
+		marker := " "
+		if rc.Matches > 0 {
+			covered++
+		} else {
+			marker = "!"
+		}
+		fmt.Fprintf(buf, "%s %-20s %-15s %-20s %d/%d\n", marker, rc.Location(), rc.Var, rc.Match, rc.Matches, rc.Calls)
+
+		for i, n := range rc.Branches {
+			branches++
+			marker := " "
+			if n > 0 {
+				coveredBranches++
+			} else {
+				marker = "!"
+			}
+			fmt.Fprintf(buf, "%s %-20s %-15s   branch %-11d %d\n", marker, "", "", i+1, n)
+		}
+	}
+
+	fmt.Fprintf(buf, "\n%d/%d parsers matched, %d/%d Any branches matched\n", covered, rules, coveredBranches, branches)
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+type coverageLine struct {
+	No     int
+	Text   string
+	Class  string
+	Detail string
+}
+
+type coverageFile struct {
+	Name  string
+	Lines []coverageLine
+}
+
+var coverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>goparsify coverage</title>
+<style>
+body { font-family: monospace; background: #fff; color: #333; }
+h2 { font-family: sans-serif; font-size: 1em; margin-top: 2em; }
+pre { margin: 0; }
+.line { white-space: pre; }
+.no { display: inline-block; width: 4em; color: #999; text-align: right; margin-right: 1em; }
+.covered { background: #cfc; }
+.partial { background: #ffc; }
+.uncovered { background: #fcc; }
+</style>
+</head>
+<body>
+{{range .}}<h2>{{.Name}}</h2>
+<pre>{{range .Lines}}<div class="line {{.Class}}" title="{{.Detail}}"><span class="no">{{if .No}}{{.No}}{{end}}</span>{{.Text}}</div>{{end}}</pre>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML writes the source of every file that defines a parser, with each definition highlighted by how
+// much of it was covered. Hovering over a line shows the counts for the parsers defined on it. Parsers whose
+// definition cant be found are listed by name at the end.
+func (c Coverage) WriteHTML(w io.Writer) error {
+	byFile := map[string]map[int][]RuleCoverage{}
+	var files []string
+	// parsers whose definition couldnt be found are listed by name instead
+	byName := map[string][]RuleCoverage{}
+	var names []string
+	for _, rc := range c {
+		if rc.File == "" {
+			name := rc.name()
+			if byName[name] == nil {
+				names = append(names, name)
+			}
+			byName[name] = append(byName[name], rc)
+			continue
+		}
+		if byFile[rc.File] == nil {
+			byFile[rc.File] = map[int][]RuleCoverage{}
+			files = append(files, rc.File)
+		}
+		byFile[rc.File][rc.Line] = append(byFile[rc.File][rc.Line], rc)
+	}
+	sort.Strings(files)
+
+	var report []coverageFile
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		cf := coverageFile{Name: file}
+		for i, text := range strings.Split(string(src), "\n") {
+			line := coverageLine{No: i + 1, Text: text}
+			if rules, ok := byFile[file][i+1]; ok {
+				line.Class, line.Detail = describeCoverage(rules)
+			}
+			cf.Lines = append(cf.Lines, line)
+		}
+		report = append(report, cf)
+	}
+
+	if len(names) > 0 {
+		sort.Strings(names)
+		cf := coverageFile{Name: "defined elsewhere"}
+		for _, name := range names {
+			line := coverageLine{Text: name}
+			line.Class, line.Detail = describeCoverage(byName[name])
+			cf.Lines = append(cf.Lines, line)
+		}
+		report = append(report, cf)
+	}
+
+	return coverageTemplate.Execute(w, report)
+}
+
+func describeCoverage(rules []RuleCoverage) (class string, detail string) {
+	covered := 0
+	var details []string
+	for _, rc := range rules {
+		if rc.Covered() {
+			covered++
+		}
+		d := fmt.Sprintf("%s %s: %d/%d", rc.Var, rc.Match, rc.Matches, rc.Calls)
+		for i, n := range rc.Branches {
+			d += fmt.Sprintf(", branch %d: %d", i+1, n)
+		}
+		details = append(details, strings.TrimSpace(d))
+	}
+
+	switch covered {
+	case len(rules):
+		class = "covered"
+	case 0:
+		class = "uncovered"
+		for _, rc := range rules {
+			if rc.Matches > 0 {
+				class = "partial"
+			}
+		}
+	default:
+		class = "partial"
+	}
+	return class, strings.Join(details, "\n")
+}
@@ -0,0 +1,145 @@
+package goparsify
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage(t *testing.T) {
+	ResetCoverage()
+	coverKeyword := Any("select", "insert", "delete")
+	coverStatement := Seq(coverKeyword, Chars("a-z"))
+	EnableCoverage()
+	_, err := Run(coverStatement, "select foo")
+	require.NoError(t, err)
+	_, err = Run(coverStatement, "delete")
+	require.Error(t, err)
+	DisableCoverage()
+
+	// not counted
+	_, err = Run(coverStatement, "insert foo")
+	require.NoError(t, err)
+
+	coverage := GetCoverage().Filter(func(rc RuleCoverage) bool {
+		return rc.Var == "coverKeyword" || rc.Var == "coverStatement"
+	})
+
+	t.Run("counts parsers and branches", func(t *testing.T) {
+		byMatch := map[string]RuleCoverage{}
+		for _, rc := range coverage {
+			byMatch[rc.Match] = rc
+		}
+
+		require.Equal(t, 2, byMatch["Seq()"].Calls)
+		require.Equal(t, 1, byMatch["Seq()"].Matches)
+		require.Equal(t, 2, byMatch["Any()"].Calls)
+		require.Equal(t, 2, byMatch["Any()"].Matches)
+		require.Equal(t, []int{1, 0, 1}, byMatch["Any()"].Branches)
+		require.Equal(t, 0, byMatch["insert"].Matches)
+		require.Equal(t, 1, byMatch["[a-z]"].Matches)
+		require.Equal(t, "coverage_test.go:13", byMatch["Any()"].Location())
+	})
+
+	t.Run("uncovered", func(t *testing.T) {
+		var uncovered []string
+		for _, rc := range coverage.Uncovered() {
+			uncovered = append(uncovered, rc.Match)
+		}
+		require.ElementsMatch(t, []string{"Any()", "insert"}, uncovered)
+	})
+
+	t.Run("text report", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, coverage.WriteText(buf))
+		require.Contains(t, buf.String(), "! coverage_test.go:13  coverKeyword    insert               0/1\n")
+		require.Contains(t, buf.String(), "!                                        branch 2           0\n")
+		require.Contains(t, buf.String(), "5/6 parsers matched, 2/3 Any branches matched")
+	})
+
+	t.Run("html report", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, coverage.WriteHTML(buf))
+		require.Contains(t, buf.String(), `<div class="line partial" title="coverKeyword select: 1/2`)
+		require.Contains(t, buf.String(), "coverKeyword Any(): 2/2, branch 1: 1, branch 2: 0, branch 3: 1")
+		require.Contains(t, buf.String(), `<div class="line covered" title="coverStatement [a-z]: 1/2`)
+	})
+}
+
+func TestCoverageOfCustomParsers(t *testing.T) {
+	coverCustom := NewParser("custom", func(ps *State, node *Result) {
+		ps.Advance(1)
+	})
+	ResetCoverage()
+	EnableCoverage()
+	_, err := Run(coverCustom, "x")
+	DisableCoverage()
+	require.NoError(t, err)
+
+	coverage := GetCoverage().Filter(func(rc RuleCoverage) bool { return rc.Var == "coverCustom" })
+	require.Len(t, coverage, 1)
+	require.Equal(t, "custom", coverage[0].Match)
+	require.Equal(t, 1, coverage[0].Matches)
+}
+
+func TestCoverageWithoutLocations(t *testing.T) {
+	coverage := Coverage{
+		{Var: "keyword", Match: "Any()", Calls: 2, Matches: 1, Branches: []int{1, 0}},
+		{Match: "x", Calls: 1, Matches: 1},
+	}
+
+	t.Run("text report", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, coverage.WriteText(buf))
+		require.Contains(t, buf.String(), "  ?                    keyword         Any()                1/2\n")
+		require.Contains(t, buf.String(), "  ?                                    x                    1/1\n")
+	})
+
+	t.Run("html report", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, coverage.WriteHTML(buf))
+		require.Contains(t, buf.String(), "<h2>defined elsewhere</h2>")
+		require.Contains(t, buf.String(), `<div class="line partial" title="keyword Any(): 1/2, branch 1: 1, branch 2: 0"><span class="no"></span>keyword</div>`)
+		require.Contains(t, buf.String(), `<div class="line covered" title="x: 1/1"><span class="no"></span>x</div>`)
+	})
+}
+
+func TestCoverageOfDynamicParsers(t *testing.T) {
+	listed := func() int {
+		registry.Lock()
+		defer registry.Unlock()
+		return len(registry.order)
+	}
+	repeated := Chain(Chars("a-z"), func(n *Result) Parserish { return Exact(n.Token) })
+
+	t.Run("arent kept when not covering", func(t *testing.T) {
+		before := listed()
+		for i := 0; i < 100; i++ {
+			ps := NewState("a a")
+			ps.Tracer = NewTracer(nil)
+			repeated(ps, NewResult(ps.Input))
+			require.False(t, ps.Errored())
+		}
+		require.Equal(t, before, listed())
+	})
+
+	t.Run("share a rule when covering", func(t *testing.T) {
+		EnableCoverage()
+		for i := 0; i < 100; i++ {
+			_, err := Run(repeated, "a a")
+			require.NoError(t, err)
+		}
+		DisableCoverage()
+
+		var rules []RuleCoverage
+		for _, rc := range GetCoverage() {
+			if rc.Match == "a" && strings.HasPrefix(rc.Location(), "coverage_test.go:") {
+				rules = append(rules, rc)
+			}
+		}
+		require.Len(t, rules, 1)
+		require.Equal(t, 100, rules[0].Matches)
+	})
+}
@@ -13,14 +13,14 @@ import (
 var varRegex = regexp.MustCompile(`(?:var)?\s*(\w*)\s*:?=`)
 
 func getPackageName(f runtime.Frame) string {
-	parts := strings.Split(f.Func.Name(), ".")
-	pl := len(parts)
-
-	if parts[pl-2][0] == '(' {
-		return strings.Join(parts[0:pl-2], ".")
+	// the package path may itself contain dots (github.com/...), so only look for the
+	// separating dot after the last slash.
+	slash := strings.LastIndex(f.Function, "/")
+	if dot := strings.Index(f.Function[slash+1:], "."); dot >= 0 {
+		return f.Function[:slash+1+dot]
 	}
 
-	return strings.Join(parts[0:pl-1], ".")
+	return f.Function
 }
 
 func getVarName(filename string, lineNo int) string {
@@ -45,22 +45,70 @@ func getVarName(filename string, lineNo int) string {
 func GetDefinition() (varName string, location string) {
 	pc := make([]uintptr, 64)
 	n := runtime.Callers(3, pc)
-	frames := runtime.CallersFrames(pc[:n])
+	return Definition(pc[:n])
+}
+
+// Callers captures just enough of the current stack for Definition to find where a parser was defined later on.
+// It is much cheaper than GetDefinition because nothing is resolved until Definition is called.
+func Callers() []uintptr {
+	pc := make([]uintptr, 16)
+	n := runtime.Callers(3, pc)
+	return pc[:n:n]
+}
+
+// Definition returns the name of the variable and location of the first frame outside of goparsify in a stack
+// captured by Callers
+func Definition(pc []uintptr) (varName string, location string) {
+	site := Locate(pc)
+	if site.Var == "" {
+		return "", ""
+	}
+	return site.Var, site.Location()
+}
+
+// Site is the place in the source a parser was defined
+type Site struct {
+	// Var is the name of the variable the parser was assigned to
+	Var  string
+	File string
+	Line int
+}
+
+// Location is the short file:line form of the site, or "" if it is unknown
+func (s Site) Location() string {
+	if s.File == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(s.File), s.Line)
+}
 
+// Locate finds the first frame outside of goparsify in a stack captured by Callers that assigns to a variable.
+// If no such frame exists the first frame outside of goparsify is returned without a Var.
+func Locate(pc []uintptr) Site {
+	frames := runtime.CallersFrames(pc)
+
+	var fallback Site
 	var frame runtime.Frame
 	more := true
 	for more {
 		frame, more = frames.Next()
 		pkg := getPackageName(frame)
-		if pkg == "github.com/ajitid/goparsify" || pkg == "github.com/ajitid/goparsify/debug" {
+		// goparsify's own tests define parsers too, so only skip the library code itself
+		if (pkg == "github.com/ajitid/goparsify" || pkg == "github.com/ajitid/goparsify/debug") && !strings.HasSuffix(frame.File, "_test.go") {
+			continue
+		}
+		if frame.File == "" {
 			continue
 		}
 
 		varName := getVarName(frame.File, frame.Line)
 		if varName != "" {
-			return varName, fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+			return Site{Var: varName, File: frame.File, Line: frame.Line}
+		}
+		if fallback.File == "" {
+			fallback = Site{File: frame.File, Line: frame.Line}
 		}
 	}
 
-	return "", ""
+	return fallback
 }
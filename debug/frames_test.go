@@ -1,6 +1,7 @@
 package debug
 
 import (
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -22,3 +23,19 @@ func TestRegex(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPackageName(t *testing.T) {
+	tests := map[string]string{
+		"github.com/ajitid/goparsify.Exact":               "github.com/ajitid/goparsify",
+		"github.com/ajitid/goparsify.Exact.func1":         "github.com/ajitid/goparsify",
+		"github.com/ajitid/goparsify.(*State).WS":         "github.com/ajitid/goparsify",
+		"github.com/ajitid/goparsify/json.init":           "github.com/ajitid/goparsify/json",
+		"github.com/ajitid/goparsify/debug.GetDefinition": "github.com/ajitid/goparsify/debug",
+		"main.main": "main",
+	}
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			require.Equal(t, expected, getPackageName(runtime.Frame{Function: input}))
+		})
+	}
+}
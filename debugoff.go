@@ -1,14 +1,18 @@
+//go:build !debug
 // +build !debug
 
 package goparsify
 
-import "io"
+import (
+	"io"
+)
 
-// NewParser should be called around the creation of every Parser.
-// It does nothing normally and should incur no runtime overhead, but when building with -tags debug
-// it will instrument every parser to collect valuable timing information displayable with DumpDebugStats.
-func NewParser(description string, p Parser) Parser {
-	return p
+// defaultTracer is the Tracer of a new State, which only counts coverage when it is enabled
+func defaultTracer() *Tracer {
+	if covering.Load() {
+		return coverageTracer
+	}
+	return nil
 }
 
 // DumpDebugStats will print out the curring timings for each parser if built with -tags debug
@@ -0,0 +1,185 @@
+package goparsify
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// byteSet is a set of bytes, one bit each
+type byteSet [4]uint64
+
+func (s *byteSet) add(b byte) {
+	s[b>>6] |= 1 << (b & 63)
+}
+
+func (s *byteSet) has(b byte) bool {
+	return s[b>>6]&(1<<(b&63)) != 0
+}
+
+// addFolds adds the first byte of every other case of the first rune of str
+func (s *byteSet) addFolds(str string) {
+	buf := make([]byte, utf8.UTFMax)
+	r, _ := utf8.DecodeRuneInString(str)
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		utf8.EncodeRune(buf, f)
+		s.add(buf[0])
+	}
+}
+
+func (s *byteSet) union(other *byteSet) {
+	for i := range s {
+		s[i] |= other[i]
+	}
+}
+
+// firstBytes is what is known about how a parser starts, once whitespace has been skipped
+type firstBytes struct {
+	// bytes it can start with, it is sure to fail on anything else
+	bytes byteSet
+	// expected is the error it fails with when it doesnt start with one of bytes
+	expected string
+}
+
+// dispatchTable is a jump table for Any. For every byte, it has a bit set for each alternative that could
+// start with that byte. The alternatives without a bit are sure to fail, with the error in expected.
+type dispatchTable struct {
+	viable   [256]uint64
+	expected []string
+}
+
+// newDispatchTable builds a jump table for the alternatives of Any, or returns nil if it wouldnt help. fold is
+// whether the Any is inside of FoldCase.
+func newDispatchTable(alternatives []*Grammar, fold bool) *dispatchTable {
+	if len(alternatives) > 64 {
+		return nil
+	}
+
+	table := &dispatchTable{expected: make([]string, len(alternatives))}
+	predicted := false
+	for i, alt := range alternatives {
+		first, ok := firstBytesOf(alt, fold, map[*Grammar]bool{})
+		for b := 0; b < 256; b++ {
+			if !ok || first.bytes.has(byte(b)) {
+				table.viable[b] |= 1 << uint(i)
+			}
+		}
+		if ok {
+			table.expected[i] = first.expected
+			predicted = true
+		}
+	}
+
+	if !predicted {
+		return nil
+	}
+	return table
+}
+
+// firstBytesOf works out how g starts from its grammar. It is conservative, any parser it cant be sure about
+// is not ok, so it is always tried.
+func firstBytesOf(g *Grammar, fold bool, visiting map[*Grammar]bool) (first firstBytes, ok bool) {
+	if visiting[g] {
+		return first, false
+	}
+	visiting[g] = true
+	defer delete(visiting, g)
+
+	switch g.Kind {
+	case GrammarExact:
+		if g.Literal == "" {
+			return first, false
+		}
+		first.bytes.add(g.Literal[0])
+		if fold || g.FoldCase {
+			first.bytes.addFolds(g.Literal)
+		}
+		first.expected = g.Literal
+		return first, true
+
+	case GrammarChars, GrammarNotChars:
+		if g.Min < 1 {
+			return first, false
+		}
+		stopOn := g.Kind == GrammarNotChars
+		for b := 0; b < 256; b++ {
+			// anything outside of ascii may be the start of a rune that matches
+			if b >= 0x80 || g.Contains(rune(b)) != stopOn {
+				first.bytes.add(byte(b))
+			}
+		}
+		first.expected = g.Literal
+		return first, true
+
+	case GrammarStringLit:
+		if g.Literal == "" {
+			return first, false
+		}
+		for i := 0; i < len(g.Literal); i++ {
+			first.bytes.add(g.Literal[i])
+		}
+		for i := 0; i < len(g.starts); i++ {
+			first.bytes.add(g.starts[i])
+		}
+		first.expected = g.Literal
+		return first, true
+
+	case GrammarNumberLit:
+		for _, b := range []byte("0123456789+-." + g.starts) {
+			first.bytes.add(b)
+		}
+		first.expected = "number"
+		return first, true
+
+	case GrammarOneOf:
+		if len(g.Literals) == 0 {
+			return first, false
+		}
+		for _, literal := range g.Literals {
+			if literal == "" {
+				return first, false
+			}
+			first.bytes.add(literal[0])
+			if g.FoldCase {
+				first.bytes.addFolds(literal)
+			}
+		}
+		first.expected = describeLiterals(g.Literals)
+		return first, true
+
+	case GrammarSeq, GrammarNoAutoWS, GrammarCapture:
+		children := g.Children()
+		if len(children) == 0 {
+			return first, false
+		}
+		return firstBytesOf(children[0], fold, visiting)
+
+	case GrammarFoldCase:
+		return firstBytesOf(g.Children()[0], true, visiting)
+
+	case GrammarMany:
+		if g.Min < 1 {
+			return first, false
+		}
+		return firstBytesOf(g.Children()[0], fold, visiting)
+
+	case GrammarAny:
+		children := g.Children()
+		if len(children) == 0 {
+			return first, false
+		}
+		expected := make([]string, 0, len(children))
+		for _, child := range children {
+			childFirst, ok := firstBytesOf(child, fold, visiting)
+			if !ok {
+				return first, false
+			}
+			first.bytes.union(&childFirst.bytes)
+			expected = append(expected, childFirst.expected)
+		}
+		first.expected = strings.Join(expected, " or ")
+		return first, true
+	}
+
+	return first, false
+}
@@ -0,0 +1,169 @@
+package goparsify
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireSameAsUndispatched runs parser with and without Any's jump tables, tracing turns them off
+func requireSameAsUndispatched(t *testing.T, parser Parser, input string) {
+	t.Helper()
+
+	fast, fastState := runParser(input, parser)
+
+	ps := NewState(input)
+	ps.Tracer = NewTracer(nil)
+	slow := Result{}
+	parser(ps, &slow)
+
+	require.Equal(t, ps.Errored(), fastState.Errored(), input)
+	require.Equal(t, ps.Pos, fastState.Pos, input)
+	if ps.Errored() {
+		require.Equal(t, ps.Error.Error(), fastState.Error.Error(), input)
+		return
+	}
+	require.Equal(t, slow, fast, input)
+}
+
+func TestDispatchTable(t *testing.T) {
+	t.Run("predicts literals", func(t *testing.T) {
+		table := newDispatchTable([]*Grammar{
+			Describe("null"),
+			Describe(Chars("a-c", 2)),
+			Describe(NotChars("x")),
+			Describe(StringLit(`"'`)),
+			Describe(Map(NumberLit(), func(n *Result) {})),
+			Describe(Seq("[", Cut(), "]")),
+			Describe(Any("{", OneOrMore("<"))),
+		}, false)
+		require.NotNil(t, table)
+
+		viable := func(b byte) []int {
+			ret := []int{}
+			for i := 0; i < 7; i++ {
+				if table.viable[b]&(1<<uint(i)) != 0 {
+					ret = append(ret, i)
+				}
+			}
+			return ret
+		}
+		require.Equal(t, []int{0, 2}, viable('n'))
+		require.Equal(t, []int{1, 2}, viable('b'))
+		require.Equal(t, []int{}, viable('x'))
+		require.Equal(t, []int{2, 3}, viable('\''))
+		require.Equal(t, []int{2, 4}, viable('-'))
+		require.Equal(t, []int{2, 5}, viable('['))
+		require.Equal(t, []int{2, 6}, viable('<'))
+		require.Equal(t, []int{1, 2}, viable(0xc3))
+		require.Equal(t, []string{"null", "a-c", "x", `"'`, "number", "[", "{ or <"}, table.expected)
+	})
+
+	t.Run("tries anything it cant predict", func(t *testing.T) {
+		require.Nil(t, newDispatchTable([]*Grammar{
+			Describe(Regex("a")),
+			Describe(Maybe("b")),
+			Describe(Chars("a-z", 0)),
+			Describe(Seq(Cut(), "x")),
+			Describe(Until("x")),
+			Describe(Exact("")),
+			Describe(func(ps *State, node *Result) {}),
+		}, false))
+	})
+
+	t.Run("isnt fooled by wrappers that consume first", func(t *testing.T) {
+		wrapper := func(ps *State, node *Result) {
+			ps.Pos++
+			Exact("b")(ps, node)
+		}
+		require.Equal(t, GrammarOpaque, Describe(wrapper).Kind)
+	})
+
+	t.Run("handles left recursion", func(t *testing.T) {
+		// only described, as parsing a left recursive grammar never ends
+		var value Parser
+		value = Any(Seq(&value, "+"), "1")
+		require.Nil(t, newDispatchTable([]*Grammar{Describe(value)}, false))
+	})
+
+	t.Run("folds case", func(t *testing.T) {
+		alternatives := []*Grammar{Describe("select"), Describe(ExactFold("kind")), Describe(FoldCase("in"))}
+		table := newDispatchTable(alternatives, false)
+		require.Equal(t, uint64(1), table.viable['s'])
+		require.Equal(t, uint64(0), table.viable['S'])
+		require.Equal(t, uint64(2), table.viable['K'])
+		require.Equal(t, uint64(2), table.viable[0xe2]) // the kelvin sign
+		require.Equal(t, uint64(4), table.viable['I'])
+
+		table = newDispatchTable(alternatives, true)
+		require.Equal(t, uint64(1), table.viable['S'])
+		require.Equal(t, uint64(1), table.viable[0xc5]) // the long s
+	})
+}
+
+func TestDispatchErrors(t *testing.T) {
+	var value Parser
+	array := Seq("[", Cut(), ZeroOrMore(&value, ","), "]")
+	value = Any(Bind("null", nil), Bind("true", true), StringLit(`"`), NumberLit(), array)
+
+	t.Run("same message when nothing is viable", func(t *testing.T) {
+		_, ps := runParser("x", value)
+		require.Equal(t, `offset 0: expected null or true or " or number or [`, ps.Error.Error())
+	})
+
+	t.Run("same message when viable branches fail", func(t *testing.T) {
+		_, ps := runParser("[1, nul]", value)
+		require.Equal(t, `offset 4: expected null or true or " or number or ]`, ps.Error.Error())
+		requireSameAsUndispatched(t, value, "[1, nul]")
+		requireSameAsUndispatched(t, value, "[1, t")
+		requireSameAsUndispatched(t, value, "nulx")
+	})
+}
+
+// dispatchGrammar mixes alternatives that can be dispatched on with ones that cant
+func dispatchGrammar() (value Parser, list Parser) {
+	list = Seq("(", Cut(), ZeroOrMore(&value, ","), ")")
+	value = Any(
+		"null",
+		Seq("n", "o"),
+		NumberLit(),
+		StringLit(`"'`),
+		Chars("a-f", 2, 3),
+		NotChars("(),\"' 0-9"),
+		Any(Seq("<", Chars("a-z"), ">"), OneOrMore("!")),
+		Maybe("?"),
+		list,
+	)
+	return value, list
+}
+
+func TestDispatchEquivalence(t *testing.T) {
+	value, list := dispatchGrammar()
+
+	alphabet := []byte(`nulo0123.-+e"'abcfxyz<>!?(), é`)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		input := make([]byte, r.Intn(12))
+		for j := range input {
+			input[j] = alphabet[r.Intn(len(alphabet))]
+		}
+		requireSameAsUndispatched(t, value, string(input))
+		requireSameAsUndispatched(t, list, string(input))
+	}
+}
+
+func TestDispatchFoldCase(t *testing.T) {
+	keywords := Any("null", "nil", ExactFold("in"), Seq("(", "x", ")"), "é")
+	parser := Any(FoldCase(Seq("[", ZeroOrMore(keywords, ","), "]")), ZeroOrMore(keywords))
+
+	alphabet := []string{"n", "N", "u", "U", "l", "L", "i", "I", "x", "X", "é", "É", "[", "]", "(", ")", ",", " "}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		input := ""
+		for j := r.Intn(12); j > 0; j-- {
+			input += alphabet[r.Intn(len(alphabet))]
+		}
+		requireSameAsUndispatched(t, parser, input)
+	}
+}
@@ -1,7 +1,6 @@
 package goparsify
 
 import (
-	"bufio"
 	"fmt"
 	"strings"
 )
@@ -11,13 +10,20 @@ import (
 type Error struct {
 	pos      int
 	expected string
+	// unexpected is what was found instead, when that says more than what was expected, see parseError
+	unexpected string
 }
 
 // Pos is the offset into the document the error was found
 func (e *Error) Pos() int { return e.pos }
 
 // Error satisfies the golang error interface
-func (e *Error) Error() string { return fmt.Sprintf("offset %d: expected %s", e.pos, e.expected) }
+func (e *Error) Error() string {
+	if e.unexpected != "" {
+		return fmt.Sprintf("offset %d: unexpected %s", e.pos, e.unexpected)
+	}
+	return fmt.Sprintf("offset %d: expected %s", e.pos, e.expected)
+}
 
 // UnparsedInputError is returned by Run when not all of the input was consumed. There may still be a valid result
 type UnparsedInputError struct {
@@ -32,36 +38,35 @@ func (e UnparsedInputError) Error() string {
 // LocalError locates the error position in the input string s and returns the
 // error description along with a cursor to the input.
 func (e *Error) LocateError(s string) string {
-	if len(s) < e.Pos() {
+	pos := e.Pos()
+	if pos < 0 || len(s) < pos {
 		return e.Error()
 	}
 
-	pos := e.Pos()
-
 	// find the line.
-	var (
-		lino, prev, off int
-		line, indent    []byte
-	)
-	byline := bufio.NewScanner(strings.NewReader(s))
-	for byline.Scan() {
-		lino++
-		line = byline.Bytes()
-		prev = off
-		off += len(line) + 1
-		// log.Printf("lino:%3d len:%4d prev:%4d  pos:%4d off:%4d rel:%4d line: %s",
-		// 	lino, len(line), prev, pos, off, (pos - prev), string(line))
-		if prev <= pos && pos <= off {
-			break
-		}
+	lineStart := strings.LastIndexByte(s[:pos], '\n') + 1
+	lineEnd := strings.IndexByte(s[pos:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(s)
+	} else {
+		lineEnd += pos
+	}
+	lino := strings.Count(s[:lineStart], "\n") + 1
+	line := []byte(strings.TrimSuffix(s[lineStart:lineEnd], "\r"))
+
+	// keep tabs in the indent so the cursor lines up with the line above it
+	off := pos - lineStart
+	if off > len(line) {
+		off = len(line)
 	}
-	indent = make([]byte, len(line[:pos-prev]))
-	for i, c := range indent {
-		if c != '\t' {
+	indent := make([]byte, off)
+	for i, c := range line[:off] {
+		if c == '\t' {
+			indent[i] = '\t'
+		} else {
 			indent[i] = ' '
 		}
 	}
-	off = pos - prev
 	if off > 40 {
 		indent = indent[off-30:]
 		line = line[off-30:]
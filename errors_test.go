@@ -0,0 +1,30 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestError_LocateError(t *testing.T) {
+	t.Run("middle of a line", func(t *testing.T) {
+		err := Error{pos: 9, expected: "world"}
+		require.Equal(t, "Parsing error in line 2:\n\thi there\n\t  ^\noffset 9: expected world\n", err.LocateError("hello\n\thi there"))
+	})
+
+	t.Run("end of input", func(t *testing.T) {
+		err := Error{pos: 4, expected: "more"}
+		require.Equal(t, "Parsing error in line 2:\n\n^\noffset 4: expected more\n", err.LocateError("abc\n"))
+	})
+
+	t.Run("long line", func(t *testing.T) {
+		input := "0123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890123456789"
+		err := Error{pos: 50, expected: "x"}
+		require.Equal(t, "Parsing error in line 1:\n...3456789012345678901234567890123456789012345678901234567890123456...\n                              ^\noffset 50: expected x\n", err.LocateError(input))
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		err := Error{pos: 10, expected: "x"}
+		require.Equal(t, "offset 10: expected x", err.LocateError("abc"))
+	})
+}
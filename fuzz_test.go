@@ -0,0 +1,197 @@
+package goparsify
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireInvariants runs parser over input, starting at offset, and checks the rules documented on Parser
+func requireInvariants(t *testing.T, parser Parser, input string, offset int) (Result, *State) {
+	t.Helper()
+	if offset < 0 || offset > len(input) {
+		offset = 0
+	}
+
+	ps := NewState(input)
+	ps.Pos = offset
+	result := Result{Input: input}
+	parser(ps, &result)
+
+	if ps.Errored() {
+		require.Equal(t, offset, ps.Pos, "a parser that errors must not change state.Pos")
+		require.True(t, ps.Error.Pos() >= 0 && ps.Error.Pos() <= len(input), "error offset %d out of range", ps.Error.Pos())
+		require.NotPanics(t, func() { ps.Error.LocateError(input) })
+		return result, ps
+	}
+
+	require.True(t, ps.Pos >= offset && ps.Pos <= len(input), "pos %d out of range", ps.Pos)
+	require.True(t, result.Start >= 0 && result.Start <= result.End && result.End <= len(input),
+		"expected 0 <= Start(%d) <= End(%d) <= %d", result.Start, result.End, len(input))
+	return result, ps
+}
+
+func FuzzStringLit(f *testing.F) {
+	for _, seed := range []string{``, `"`, `"hello"`, `'hi'`, `"a\"b"`, `"뻯"`, `"\u12`, `"\`, `"👺"`, ` "x" `} {
+		f.Add(seed, 0)
+	}
+	parser := StringLit(`"'`)
+
+	f.Fuzz(func(t *testing.T, input string, offset int) {
+		result, ps := requireInvariants(t, parser, input, offset)
+		if !ps.Errored() && utf8.ValidString(input) {
+			require.True(t, utf8.ValidString(result.Token))
+		}
+	})
+}
+
+func FuzzStringLitWith(f *testing.F) {
+	for _, seed := range []string{`"\x4`, `"\u{1F47A`, `"\ud83d\ude3a"`, `r"\"`, `'it''s'`, `"""a""`, "\"a\nb\""} {
+		f.Add(seed, 0)
+	}
+	parsers := []Parser{
+		StringLitWith(StringOptions{Quotes: `"'`, Hex: true, BracedUnicode: true, Surrogates: true, Strict: true}),
+		StringLitWith(StringOptions{Quotes: `"'`, RawPrefixes: "r", DoubledQuotes: true, TripleQuotes: true}),
+	}
+
+	f.Fuzz(func(t *testing.T, input string, offset int) {
+		for _, parser := range parsers {
+			result, ps := requireInvariants(t, parser, input, offset)
+			if !ps.Errored() && utf8.ValidString(input) {
+				require.True(t, utf8.ValidString(result.Token))
+			}
+		}
+	})
+}
+
+func FuzzNumberLit(f *testing.F) {
+	for _, seed := range []string{``, `-`, `+`, `.`, `1`, `-1.5`, `1e`, `1e+`, `.5e-3`, `99999999999999999999`, ` 12 `} {
+		f.Add(seed, 0)
+	}
+	parser := NumberLit()
+	parsers := []Parser{
+		NumberLitWith(NumberOptions{Prefixes: true, Underscores: true, InfNaN: true}),
+		NumberLitWith(NumberOptions{Strict: true}),
+		NumberLitWith(NumberOptions{Prefixes: true, Underscores: true, InfNaN: true, Result: NumberBig}),
+		NumberLitWith(NumberOptions{Prefixes: true, Underscores: true, Result: NumberDecimal}),
+	}
+
+	f.Fuzz(func(t *testing.T, input string, offset int) {
+		result, ps := requireInvariants(t, parser, input, offset)
+		if !ps.Errored() {
+			switch result.Result.(type) {
+			case int64, float64:
+			default:
+				t.Fatalf("unexpected result type %T", result.Result)
+			}
+		}
+
+		for _, parser := range parsers {
+			requireInvariants(t, parser, input, offset)
+		}
+	})
+}
+
+func FuzzBinary(f *testing.F) {
+	for _, seed := range []string{"", "\x00", "\x80\x80", "\x05abc", "\xff\xff\xff\xff\xff\xff\xff\xff\xff\x7f"} {
+		f.Add(seed, 0)
+	}
+	parsers := []Parser{
+		Byte(), Bytes(3), Uint16(binary.BigEndian), Uint64(binary.LittleEndian),
+		Uvarint(), Varint(), LEB128(), Take(Byte()), Take(Uvarint()), Take(Uint32(binary.BigEndian)),
+	}
+
+	f.Fuzz(func(t *testing.T, input string, offset int) {
+		for _, parser := range parsers {
+			requireInvariants(t, parser, input, offset)
+		}
+	})
+}
+
+func FuzzChars(f *testing.F) {
+	f.Add("a-z", "hello world", 1, -1)
+	f.Add(`\-a`, "-a-b", 0, 2)
+	f.Add("z-a0", "\xff\xfe", 2, 3)
+	f.Add(`\`, `\\`, 1, 1)
+
+	f.Fuzz(func(t *testing.T, matcher string, input string, min int, max int) {
+		if min < 0 || min > 1000 || max < -1 || max > 1000 {
+			return
+		}
+		requireInvariants(t, Chars(matcher, min, max), input, 0)
+		requireInvariants(t, NotChars(matcher, min, max), input, 0)
+	})
+}
+
+func FuzzUntil(f *testing.F) {
+	f.Add("world", "hello world", 0)
+	f.Add("", "abc", 1)
+	f.Add("long terminator", "short", 3)
+
+	f.Fuzz(func(t *testing.T, terminator string, input string, offset int) {
+		requireInvariants(t, Until(terminator, "."), input, offset)
+	})
+}
+
+func FuzzRegex(f *testing.F) {
+	f.Add("hello", 0)
+	f.Add("123 abc", 2)
+	f.Add("", 0)
+
+	parsers := []Parser{Regex("[a-z]+"), Regex("[0-9]*"), Regex(`\s*x`), Regex("(ab)+")}
+	f.Fuzz(func(t *testing.T, input string, offset int) {
+		for _, parser := range parsers {
+			requireInvariants(t, parser, input, offset)
+		}
+	})
+}
+
+func FuzzCombinators(f *testing.F) {
+	f.Add("<a>b, c d", 0)
+	f.Add("var x = (1, 2", 0)
+	f.Add("  ", 1)
+
+	ident := Chars("a-z")
+	var value Parser
+	list := Seq("(", Cut(), ZeroOrMore(&value, ","), ")")
+	value = Any(NumberLit(), StringLit(`"`), ident, &list)
+	parsers := []Parser{
+		Seq("var", ident, "=", &value),
+		OneOrMore(Any(Seq("<", Cut(), ident, ">"), ident), Maybe(",")),
+		Chain(ident, func(prevN *Result) Parserish { return prevN.Token }),
+		NoAutoWS(Seq(ident, Maybe(Exact(" ")), ident)),
+		Merge(&list),
+	}
+
+	f.Fuzz(func(t *testing.T, input string, offset int) {
+		for _, parser := range parsers {
+			requireInvariants(t, parser, input, offset)
+		}
+	})
+}
+
+func FuzzAnyDispatch(f *testing.F) {
+	f.Add("(null, no, 1.5, 'x', abc, <a>, !!, ?)")
+	f.Add("(nul")
+	f.Add("é")
+
+	value, list := dispatchGrammar()
+	f.Fuzz(func(t *testing.T, input string) {
+		requireSameAsUndispatched(t, value, input)
+		requireSameAsUndispatched(t, list, input)
+	})
+}
+
+func FuzzLocateError(f *testing.F) {
+	f.Add("hello\nworld", 6)
+	f.Add("abc\n", 4)
+	f.Add("\t\tfoo", 3)
+	f.Add("", 0)
+
+	f.Fuzz(func(t *testing.T, input string, pos int) {
+		err := Error{pos: pos, expected: "something"}
+		require.NotPanics(t, func() { err.LocateError(input) })
+	})
+}
@@ -0,0 +1,669 @@
+// Package generate produces random sample inputs from a goparsify grammar, for use as seeds for Go's native
+// fuzzing or in property tests.
+//
+//	gen := generate.New(root, generate.Options{MaxDepth: 6})
+//
+//	func FuzzParse(f *testing.F) {
+//		f.Add(int64(1))
+//		f.Fuzz(func(t *testing.T, seed int64) {
+//			r := rand.New(rand.NewSource(seed))
+//			_, err := goparsify.Run(root, gen.Generate(r))
+//			...
+//		})
+//	}
+package generate
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ajitid/goparsify"
+)
+
+// Options limit the size of the generated inputs. Zero values are replaced with sensible defaults.
+type Options struct {
+	// MaxDepth is how deep to descend into the grammar before taking the shortest way out. Defaults to 8.
+	MaxDepth int
+	// MaxRepeat is the most extra repetitions ZeroOrMore, OneOrMore, Chars and regex repeats will generate beyond
+	// their minimum. Defaults to 4.
+	MaxRepeat int
+	// MaxSize is a soft limit on the length of an input in bytes, once reached the shortest way out is taken.
+	// Defaults to 1024.
+	MaxSize int
+	// Separator is placed between tokens that are not inside NoAutoWS. Defaults to a single space.
+	Separator string
+}
+
+// Generator produces random inputs for a grammar. It is safe for concurrent use as long as each
+// goroutine uses its own *rand.Rand.
+type Generator struct {
+	root     *goparsify.Grammar
+	opts     Options
+	minDepth map[*goparsify.Grammar]int
+	// terminals are all the grammars that produce a single token, used by mutations
+	terminals []*goparsify.Grammar
+	regexes   map[string]*syntax.Regexp
+	charPools map[*goparsify.Grammar][]rune
+}
+
+// New walks the grammar of root and prepares a Generator for it
+func New(root goparsify.Parserish, opts Options) *Generator {
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = 8
+	}
+	if opts.MaxRepeat == 0 {
+		opts.MaxRepeat = 4
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = 1024
+	}
+	if opts.Separator == "" {
+		opts.Separator = " "
+	}
+
+	g := &Generator{
+		root:      goparsify.Describe(root),
+		opts:      opts,
+		minDepth:  map[*goparsify.Grammar]int{},
+		regexes:   map[string]*syntax.Regexp{},
+		charPools: map[*goparsify.Grammar][]rune{},
+	}
+
+	var all []*goparsify.Grammar
+	var walk func(gr *goparsify.Grammar)
+	walk = func(gr *goparsify.Grammar) {
+		if gr == nil {
+			return
+		}
+		if _, seen := g.minDepth[gr]; seen {
+			return
+		}
+		g.minDepth[gr] = math.MaxInt32
+		all = append(all, gr)
+		if isTerminal(gr) {
+			g.terminals = append(g.terminals, gr)
+		}
+		if gr.Kind == goparsify.GrammarChars || gr.Kind == goparsify.GrammarNotChars {
+			g.charPools[gr] = charPool(gr)
+		}
+		if gr.Kind == goparsify.GrammarRegex {
+			if re, err := syntax.Parse(gr.Literal, syntax.Perl); err == nil {
+				g.regexes[gr.Literal] = re.Simplify()
+			}
+		}
+		for _, child := range gr.Children() {
+			walk(child)
+		}
+		walk(gr.Separator())
+	}
+	walk(g.root)
+
+	// the grammar is usually recursive, so find the shortest derivation of each rule by iterating until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, gr := range all {
+			if d := g.depthOf(gr); d < g.minDepth[gr] {
+				g.minDepth[gr] = d
+				changed = true
+			}
+		}
+	}
+
+	return g
+}
+
+func isTerminal(gr *goparsify.Grammar) bool {
+	switch gr.Kind {
+	case goparsify.GrammarExact, goparsify.GrammarChars, goparsify.GrammarNotChars, goparsify.GrammarRegex,
+		goparsify.GrammarStringLit, goparsify.GrammarNumberLit, goparsify.GrammarUntil, goparsify.GrammarOneOf,
+		goparsify.GrammarBackref, goparsify.GrammarUntilBackref,
+		goparsify.GrammarBytes, goparsify.GrammarVarint, goparsify.GrammarTake, goparsify.GrammarToken:
+		return true
+	}
+	return false
+}
+
+func (g *Generator) depthOf(gr *goparsify.Grammar) int {
+	children := gr.Children()
+	switch gr.Kind {
+	case goparsify.GrammarSeq:
+		d := 0
+		for _, child := range children {
+			if g.minDepth[child] > d {
+				d = g.minDepth[child]
+			}
+		}
+		return d + 1
+	case goparsify.GrammarAny:
+		d := math.MaxInt32
+		for _, child := range children {
+			if g.minDepth[child] < d {
+				d = g.minDepth[child]
+			}
+		}
+		return d + 1
+	case goparsify.GrammarMany:
+		if gr.Min == 0 {
+			return 1
+		}
+		d := g.minDepth[children[0]]
+		if sep := gr.Separator(); sep != nil && (gr.Min > 1 || gr.Trailing == goparsify.TrailingRequired) && g.minDepth[sep] > d {
+			d = g.minDepth[sep]
+		}
+		return d + 1
+	case goparsify.GrammarNoAutoWS, goparsify.GrammarFoldCase, goparsify.GrammarCapture:
+		return g.minDepth[children[0]] + 1
+	default:
+		return 1
+	}
+}
+
+type token struct {
+	text string
+	// autoWS is true when the token was generated where whitespace is skipped automatically
+	autoWS bool
+}
+
+type generation struct {
+	*Generator
+	r      *rand.Rand
+	tokens []token
+	size   int
+	autoWS bool
+	// foldCase is true inside of FoldCase
+	foldCase bool
+	// captures are the text generated for each Capture, for Backref to repeat
+	captures map[string]string
+}
+
+// Generate returns a random input that matches the grammar.
+//
+// The generator only knows the structure of the grammar, not how PEG ordering will play out, so a small number of
+// inputs may still fail to parse, eg when a greedy Chars swallows a keyword that was meant to follow it.
+func (g *Generator) Generate(r *rand.Rand) string {
+	return join(g.generate(r), g.opts.Separator)
+}
+
+// GenerateInvalid returns a near valid input, by generating a valid input and then introducing a single
+// token error: deleting, duplicating or replacing a token, or inserting a random one. The result is usually,
+// but not always, invalid.
+func (g *Generator) GenerateInvalid(r *rand.Rand) string {
+	tokens := g.generate(r)
+	gen := &generation{Generator: g, r: r, autoWS: true}
+
+	if len(g.terminals) > 0 {
+		gen.gen(g.terminals[r.Intn(len(g.terminals))], g.opts.MaxDepth)
+	}
+	randomToken := gen.tokens
+
+	if len(tokens) == 0 {
+		return join(randomToken, g.opts.Separator)
+	}
+
+	i := r.Intn(len(tokens))
+	mutated := append([]token{}, tokens[:i]...)
+	switch r.Intn(4) {
+	case 0: // delete
+		mutated = append(mutated, tokens[i+1:]...)
+	case 1: // duplicate
+		mutated = append(mutated, tokens[i])
+		mutated = append(mutated, tokens[i:]...)
+	case 2: // replace
+		mutated = append(mutated, randomToken...)
+		mutated = append(mutated, tokens[i+1:]...)
+	default: // insert
+		mutated = append(mutated, randomToken...)
+		mutated = append(mutated, tokens[i:]...)
+	}
+	return join(mutated, g.opts.Separator)
+}
+
+func (g *Generator) generate(r *rand.Rand) []token {
+	gen := &generation{Generator: g, r: r, autoWS: true}
+	gen.gen(g.root, 0)
+	return gen.tokens
+}
+
+func join(tokens []token, separator string) string {
+	buf := &strings.Builder{}
+	for i, tok := range tokens {
+		if i > 0 && tok.autoWS && tokens[i-1].autoWS {
+			buf.WriteString(separator)
+		}
+		buf.WriteString(tok.text)
+	}
+	return buf.String()
+}
+
+func (gen *generation) emit(text string) {
+	gen.tokens = append(gen.tokens, token{text: text, autoWS: gen.autoWS})
+	gen.size += len(text)
+}
+
+// exhausted is true once we have gone deep or long enough and should take the shortest way out
+func (gen *generation) exhausted(depth int) bool {
+	return depth >= gen.opts.MaxDepth || gen.size >= gen.opts.MaxSize
+}
+
+// repeat picks how many times to repeat something, given its limits
+func (gen *generation) repeat(depth int, min int, max int) int {
+	if gen.exhausted(depth) {
+		return min
+	}
+	n := min + gen.r.Intn(gen.opts.MaxRepeat+1)
+	if max >= 0 && n > max {
+		n = max
+	}
+	return n
+}
+
+func (gen *generation) gen(gr *goparsify.Grammar, depth int) {
+	children := gr.Children()
+
+	switch gr.Kind {
+	case goparsify.GrammarSeq:
+		for _, child := range children {
+			gen.gen(child, depth+1)
+		}
+
+	case goparsify.GrammarAny:
+		gen.gen(gen.choose(children, depth), depth+1)
+
+	case goparsify.GrammarMany:
+		n := gen.repeat(depth, gr.Min, gr.Max)
+		sep := gr.Separator()
+		for i := 0; i < n; i++ {
+			if i > 0 && sep != nil && gr.Trailing != goparsify.TrailingRequired {
+				gen.gen(sep, depth+1)
+			}
+			gen.gen(children[0], depth+1)
+			if sep != nil && gr.Trailing == goparsify.TrailingRequired {
+				gen.gen(sep, depth+1)
+			}
+		}
+		if n > 0 && sep != nil && gr.Trailing == goparsify.TrailingOptional && !gen.exhausted(depth) && gen.r.Intn(4) == 0 {
+			gen.gen(sep, depth+1)
+		}
+
+	case goparsify.GrammarMaybe:
+		if !gen.exhausted(depth) && gen.r.Intn(2) == 0 {
+			gen.gen(children[0], depth+1)
+		}
+
+	case goparsify.GrammarNoAutoWS:
+		oldWS := gen.autoWS
+		gen.autoWS = false
+		gen.gen(children[0], depth+1)
+		gen.autoWS = oldWS
+
+	case goparsify.GrammarCapture:
+		before := len(gen.tokens)
+		gen.gen(children[0], depth+1)
+		if gen.captures == nil {
+			gen.captures = map[string]string{}
+		}
+		gen.captures[gr.Literal] = join(gen.tokens[before:], gen.opts.Separator)
+
+	case goparsify.GrammarBackref:
+		gen.emit(gen.captures[gr.Literal])
+
+	case goparsify.GrammarUntilBackref:
+		gen.emit(gen.until([]string{gen.captures[gr.Literal]}, depth))
+
+	case goparsify.GrammarBytes:
+		gen.emitBytes(gen.randomBytes(gr.Min))
+
+	case goparsify.GrammarVarint:
+		// mostly small numbers, as they are the most common
+		v := gen.r.Int63() >> uint(gen.r.Intn(64))
+		if gr.Literal != "uvarint" && gen.r.Intn(2) == 0 {
+			v = -v
+		}
+		gen.emitBytes(appendVarint(nil, gr.Literal, v))
+
+	case goparsify.GrammarTake:
+		n := gen.repeat(depth, 0, -1)
+		if !gen.emitLength(children[0], n) {
+			gen.gen(children[0], depth+1)
+		}
+		gen.emitBytes(gen.randomBytes(n))
+
+	case goparsify.GrammarFoldCase:
+		oldFold := gen.foldCase
+		gen.foldCase = true
+		gen.gen(children[0], depth+1)
+		gen.foldCase = oldFold
+
+	case goparsify.GrammarExact:
+		gen.emit(gen.mixCase(gr.Literal, gr.FoldCase || gen.foldCase))
+
+	case goparsify.GrammarChars, goparsify.GrammarNotChars:
+		gen.emit(gen.chars(gr, depth))
+
+	case goparsify.GrammarRegex:
+		if re, ok := gen.regexes[gr.Literal]; ok {
+			buf := &strings.Builder{}
+			gen.regex(buf, re, depth)
+			gen.emit(buf.String())
+		}
+
+	case goparsify.GrammarStringLit:
+		gen.emit(gen.stringLit(gr.StringOptions, depth))
+
+	case goparsify.GrammarNumberLit:
+		gen.emit(gen.numberLit())
+
+	case goparsify.GrammarUntil:
+		gen.emit(gen.until(gr.Terminators, depth))
+
+	case goparsify.GrammarOneOf:
+		if len(gr.Literals) > 0 {
+			gen.emit(gen.mixCase(gr.Literals[gen.r.Intn(len(gr.Literals))], gr.FoldCase))
+		}
+
+	case goparsify.GrammarToken:
+		// only tokens with known texts can be generated, what makes a token of some kind is up to the lexer
+		if len(gr.Literals) > 0 {
+			gen.emit(gr.Literals[gen.r.Intn(len(gr.Literals))])
+		}
+	}
+}
+
+// emitBytes emits binary data, which never has a separator before it as the binary parsers dont skip whitespace
+func (gen *generation) emitBytes(b []byte) {
+	gen.tokens = append(gen.tokens, token{text: string(b)})
+}
+
+func (gen *generation) randomBytes(n int) []byte {
+	b := make([]byte, n)
+	gen.r.Read(b)
+	return b
+}
+
+// emitLength emits n encoded the way the length parser of a Take reads it, or returns false if it cant tell how
+func (gen *generation) emitLength(gr *goparsify.Grammar, n int) bool {
+	switch gr.Kind {
+	case goparsify.GrammarVarint:
+		gen.emitBytes(appendVarint(nil, gr.Literal, int64(n)))
+		return true
+	case goparsify.GrammarBytes:
+		order := binary.ByteOrder(binary.BigEndian)
+		if gr.Literal == binary.LittleEndian.String() {
+			order = binary.LittleEndian
+		}
+		var b [8]byte
+		switch gr.Min {
+		case 1:
+			b[0] = byte(n)
+		case 2:
+			order.PutUint16(b[:], uint16(n))
+		case 4:
+			order.PutUint32(b[:], uint32(n))
+		case 8:
+			order.PutUint64(b[:], uint64(n))
+		default:
+			return false
+		}
+		gen.emitBytes(b[:gr.Min])
+		return true
+	}
+	return false
+}
+
+// appendVarint encodes v the way the goparsify parser of the same name reads it, see Grammar.Literal
+func appendVarint(b []byte, kind string, v int64) []byte {
+	switch kind {
+	case "varint":
+		return binary.AppendVarint(b, v)
+	case "leb128":
+		for {
+			c := byte(v & 0x7f)
+			v >>= 7
+			if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+				return append(b, c)
+			}
+			b = append(b, c|0x80)
+		}
+	}
+	return binary.AppendUvarint(b, uint64(v))
+}
+
+// mixCase randomly changes the case of the letters in literal, when it is matched regardless of case
+func (gen *generation) mixCase(literal string, fold bool) string {
+	if !fold {
+		return literal
+	}
+	buf := &strings.Builder{}
+	for _, r := range literal {
+		if gen.r.Intn(2) == 0 {
+			r = unicode.SimpleFold(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// choose picks a random alternative that can still be finished within MaxDepth, or the shallowest once exhausted
+func (gen *generation) choose(alternatives []*goparsify.Grammar, depth int) *goparsify.Grammar {
+	shallowest := alternatives[0]
+	var viable []*goparsify.Grammar
+	for _, alt := range alternatives {
+		if gen.minDepth[alt] < gen.minDepth[shallowest] {
+			shallowest = alt
+		}
+		if depth+gen.minDepth[alt] <= gen.opts.MaxDepth {
+			viable = append(viable, alt)
+		}
+	}
+
+	if gen.exhausted(depth) || len(viable) == 0 {
+		return shallowest
+	}
+	return viable[gen.r.Intn(len(viable))]
+}
+
+// printable is used whenever a character class is too wide to pick from directly
+var printable = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#$%&()*+,-./:;<=>?@[]^_{|}~")
+
+func (gen *generation) chars(gr *goparsify.Grammar, depth int) string {
+	pool := gen.charPools[gr]
+	if len(pool) == 0 {
+		return ""
+	}
+
+	min := gr.Min
+	if min < 1 {
+		min = 1
+	}
+	n := gen.repeat(depth, min, gr.Max)
+	buf := &strings.Builder{}
+	for i := 0; i < n; i++ {
+		buf.WriteRune(pool[gen.r.Intn(len(pool))])
+	}
+	return buf.String()
+}
+
+// charPool finds the characters a Chars or NotChars parser can match
+func charPool(gr *goparsify.Grammar) []rune {
+	var pool []rune
+	if gr.Kind == goparsify.GrammarNotChars {
+		for _, r := range printable {
+			if !gr.Contains(r) {
+				pool = append(pool, r)
+			}
+		}
+		return pool
+	}
+
+	ranges := gr.Ranges()
+	if ranges == nil {
+		// most of unicode, so pick from what is easy to read
+		for _, r := range printable {
+			if gr.Contains(r) {
+				pool = append(pool, r)
+			}
+		}
+		return pool
+	}
+
+	for _, rng := range ranges {
+		// everything past the BMP is only worth looking at if nothing was found in it
+		if rng[0] > 0xFFFF && len(pool) > 0 {
+			break
+		}
+		for r := rng[0]; r <= rng[1]; r++ {
+			// whitespace would be skipped before the token is matched
+			if gr.Contains(r) && !unicode.IsSpace(r) && unicode.IsPrint(r) {
+				pool = append(pool, r)
+			}
+		}
+	}
+	return pool
+}
+
+// stringChars are safe in every quoting style and mean the same thing to most other string parsers, eg encoding/json
+var stringChars = []string{"a", "b", "z", "A", "Q", "0", "9", " ", "-", "_", ".", "é", "日", `\\`, `\/`, `\u00e9`, `☺`}
+
+func (gen *generation) stringLit(opts goparsify.StringOptions, depth int) string {
+	if opts.Quotes == "" {
+		return ""
+	}
+	quote := opts.Quotes[gen.r.Intn(len(opts.Quotes))]
+
+	buf := &strings.Builder{}
+	buf.WriteByte(quote)
+	n := gen.repeat(depth, 0, -1) * 2
+	for i := 0; i < n; i++ {
+		if gen.r.Intn(10) == 0 {
+			// raw strings cant have the quote in them at all, unless it can be doubled
+			switch {
+			case opts.DoubledQuotes:
+				buf.WriteByte(quote)
+				buf.WriteByte(quote)
+				continue
+			case !opts.Raw:
+				buf.WriteByte('\\')
+				buf.WriteByte(quote)
+				continue
+			}
+		}
+		buf.WriteString(stringChars[gen.r.Intn(len(stringChars))])
+	}
+	buf.WriteByte(quote)
+	return buf.String()
+}
+
+// numberLit generates numbers in the strictest common format, json numbers
+func (gen *generation) numberLit() string {
+	buf := &strings.Builder{}
+	if gen.r.Intn(3) == 0 {
+		buf.WriteByte('-')
+	}
+	if gen.r.Intn(5) == 0 {
+		buf.WriteByte('0')
+	} else {
+		buf.WriteString(strconv.Itoa(1 + gen.r.Intn(99999)))
+	}
+	if gen.r.Intn(3) == 0 {
+		buf.WriteByte('.')
+		buf.WriteString(strconv.Itoa(gen.r.Intn(1000)))
+	}
+	if gen.r.Intn(5) == 0 {
+		buf.WriteString([]string{"e", "E", "e+", "e-"}[gen.r.Intn(4)])
+		buf.WriteString(strconv.Itoa(gen.r.Intn(20)))
+	}
+	return buf.String()
+}
+
+func (gen *generation) until(terminators []string, depth int) string {
+	n := gen.repeat(depth, 1, -1)
+	for attempt := 0; attempt < 10; attempt++ {
+		buf := &strings.Builder{}
+		for i := 0; i < n; i++ {
+			buf.WriteRune(printable[gen.r.Intn(26)])
+		}
+		s := buf.String()
+		clean := true
+		for _, terminator := range terminators {
+			if terminator != "" && strings.Contains(s, terminator) {
+				clean = false
+			}
+		}
+		if clean {
+			return s
+		}
+	}
+	return ""
+}
+
+// regex generates a string matching a parsed regular expression
+func (gen *generation) regex(buf *strings.Builder, re *syntax.Regexp, depth int) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && gen.r.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			buf.WriteRune(r)
+		}
+
+	case syntax.OpCharClass:
+		buf.WriteRune(gen.charClass(re.Rune))
+
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		buf.WriteRune(printable[gen.r.Intn(len(printable))])
+
+	case syntax.OpCapture:
+		gen.regex(buf, re.Sub[0], depth)
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := 0, -1
+		switch re.Op {
+		case syntax.OpPlus:
+			min = 1
+		case syntax.OpQuest:
+			max = 1
+		case syntax.OpRepeat:
+			min, max = re.Min, re.Max
+		}
+		n := gen.repeat(depth, min, max)
+		for i := 0; i < n; i++ {
+			gen.regex(buf, re.Sub[0], depth+1)
+		}
+
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			gen.regex(buf, sub, depth+1)
+		}
+
+	case syntax.OpAlternate:
+		gen.regex(buf, re.Sub[gen.r.Intn(len(re.Sub))], depth+1)
+	}
+}
+
+// charClass picks a rune from a regex character class, preferring printable ascii when the class is very wide
+func (gen *generation) charClass(ranges []rune) rune {
+	var ascii []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for r := ranges[i]; r <= ranges[i+1] && r < 0x7f; r++ {
+			if r > ' ' {
+				ascii = append(ascii, r)
+			}
+		}
+	}
+	if len(ascii) > 0 {
+		return ascii[gen.r.Intn(len(ascii))]
+	}
+	if len(ranges) < 2 {
+		return 'x'
+	}
+
+	i := gen.r.Intn(len(ranges)/2) * 2
+	lo, hi := ranges[i], ranges[i+1]
+	return lo + rune(gen.r.Intn(int(hi-lo)+1))
+}
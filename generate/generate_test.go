@@ -0,0 +1,154 @@
+package generate
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/ajitid/goparsify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	var value Parser
+	list := Seq("(", ZeroOrMore(&value, ","), ")")
+	value = Any(NumberLit(), StringLit(`"'`), Regex("@?[a-z][a-z0-9_]*"), Chars("+*/%", 1, 3), OneOf("<=", "=>", "::"), &list)
+
+	gen := New(&value, Options{MaxDepth: 6})
+	r := rand.New(rand.NewSource(1))
+
+	t.Run("generates valid inputs", func(t *testing.T) {
+		for i := 0; i < 500; i++ {
+			input := gen.Generate(r)
+			_, err := Run(&value, input)
+			require.NoError(t, err, input)
+		}
+	})
+
+	t.Run("generates invalid inputs", func(t *testing.T) {
+		invalid := 0
+		for i := 0; i < 500; i++ {
+			input := gen.GenerateInvalid(r)
+			if _, err := Run(&value, input); err != nil {
+				invalid++
+			}
+		}
+		require.Greater(t, invalid, 200)
+	})
+
+	t.Run("respects the size limits", func(t *testing.T) {
+		small := New(&value, Options{MaxDepth: 3, MaxRepeat: 2, MaxSize: 20})
+		for i := 0; i < 200; i++ {
+			require.Less(t, len(small.Generate(r)), 200)
+		}
+	})
+}
+
+func TestGenerateNoAutoWS(t *testing.T) {
+	// whitespace is never skipped inside NoAutoWS, so the idents are generated back to back
+	ident := NoAutoWS(Seq(Chars("a-z", 1, 1), Chars("0-9", 2, 2)))
+	gen := New(OneOrMore(ident), Options{})
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		require.Regexp(t, regexp.MustCompile(`^([a-z][0-9]{2})+$`), gen.Generate(r))
+	}
+}
+
+func TestGenerateFoldCase(t *testing.T) {
+	keyword := Any(ExactFold("select"), OneOfWith(OneOfOptions{FoldCase: true}, "from", "where"))
+	statement := FoldCase(Seq("insert", keyword, "into"))
+	gen := New(statement, Options{})
+	r := rand.New(rand.NewSource(1))
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		input := gen.Generate(r)
+		_, err := Run(statement, input)
+		require.NoError(t, err, input)
+		seen[strings.Fields(input)[0]] = true
+	}
+	require.Greater(t, len(seen), 10, "expected insert in many cases")
+}
+
+func TestGenerateCapture(t *testing.T) {
+	heredoc := Seq("<<", Capture("tag", Chars("A-Z")), NoAutoWS(Seq("\n", UntilBackref("tag", "\n"), "\n", Backref("tag"))))
+	gen := New(heredoc, Options{})
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		input := gen.Generate(r)
+		_, err := Run(heredoc, input)
+		require.NoError(t, err, input)
+		require.True(t, strings.HasSuffix(input, "\n"+strings.Fields(input)[1]), input)
+	}
+}
+
+func TestGenerateRepeat(t *testing.T) {
+	word := Chars("a-z")
+	grammar := Seq(
+		"[", SepBy(word, ","), "]",
+		"{", EndBy(word, ";"), "}",
+		"(", SepEndBy(word, ","), ")",
+		Count(2, word), Repeat(1, 2, "!"),
+	)
+	gen := New(grammar, Options{})
+	r := rand.New(rand.NewSource(1))
+
+	trailing := 0
+	for i := 0; i < 100; i++ {
+		input := gen.Generate(r)
+		_, err := Run(grammar, input)
+		require.NoError(t, err, input)
+		require.NotContains(t, input, ", ]", input)
+		if strings.Contains(input, ", )") {
+			trailing++
+		}
+	}
+	require.NotZero(t, trailing)
+}
+
+func TestGenerateBinary(t *testing.T) {
+	record := Seq(Byte(), Uint32(binary.LittleEndian), Any(Uvarint(), Varint(), LEB128()), Take(Uint16(binary.BigEndian)), Take(Uvarint()))
+	grammar := NoAutoWS(Seq("records", OneOrMore(record)))
+	gen := New(grammar, Options{})
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		input := gen.Generate(r)
+		_, err := Run(grammar, input)
+		require.NoError(t, err, "%q", input)
+	}
+}
+
+func TestGenerateStringDialects(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, opts := range []StringOptions{
+		{Quotes: `"'`},
+		{Quotes: "`", Raw: true},
+		{Quotes: `'`, DoubledQuotes: true},
+		{Quotes: `'`, Raw: true, DoubledQuotes: true},
+	} {
+		str := StringLitWith(opts)
+		gen := New(str, Options{})
+		for i := 0; i < 100; i++ {
+			input := gen.Generate(r)
+			_, err := Run(str, input)
+			require.NoError(t, err, input)
+		}
+	}
+}
+
+func TestGenerateUnicodeClasses(t *testing.T) {
+	letters := Chars(`\p{Greek}0-9`)
+	gen := New(letters, Options{})
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		input := gen.Generate(r)
+		_, err := Run(letters, input)
+		require.NoError(t, err, input)
+	}
+}
@@ -0,0 +1,283 @@
+package goparsify
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// GrammarKind says which combinator or literal created a parser
+type GrammarKind int
+
+const (
+	// GrammarOpaque is any parser that cant be looked into, eg a custom parser given to NewParser
+	GrammarOpaque GrammarKind = iota
+	GrammarSeq
+	GrammarAny
+	// GrammarMany is ZeroOrMore, OneOrMore and the other repetitions like SepBy and Count, see Min and Trailing
+	GrammarMany
+	GrammarMaybe
+	GrammarNoAutoWS
+	GrammarExact
+	GrammarChars
+	GrammarNotChars
+	GrammarRegex
+	GrammarStringLit
+	GrammarNumberLit
+	GrammarUntil
+	GrammarOneOf
+	GrammarFoldCase
+	GrammarCapture
+	GrammarBackref
+	GrammarUntilBackref
+	// GrammarBytes is Byte, Bytes and the fixed width integers like Uint32, see Min
+	GrammarBytes
+	// GrammarVarint is Uvarint, Varint and LEB128, see Literal
+	GrammarVarint
+	GrammarTake
+	// GrammarToken is Tok, see Literal and Literals
+	GrammarToken
+)
+
+// Grammar describes how a parser was built, so tools like the generate package can walk a grammar
+// without parsing anything. Use Describe to get the Grammar for a parser.
+type Grammar struct {
+	Kind GrammarKind
+	// Name is the description given to NewParser, eg "Seq()" or the literal being matched
+	Name string
+	// Literal is what is being matched:
+	//   - Exact: the exact string
+	//   - Chars and NotChars: the matcher, eg a-z0-9
+	//   - Regex: the pattern
+	//   - StringLit and StringLitWith: the allowed quotes
+	//   - Capture, Backref and UntilBackref: the name of the capture
+	//   - Uint16, Uint32 and Uint64: the byte order, eg BigEndian
+	//   - Uvarint, Varint and LEB128: uvarint, varint or leb128
+	//   - Tok: the kind of token
+	Literal string
+	// Terminators are the sequences Until stops at
+	Terminators []string
+	// Literals are the literals OneOf and OneOfMap choose between, or the texts given to Tok
+	Literals []string
+	// Min and Max are the repetition limits of Chars, NotChars and repetitions like ZeroOrMore. Max is -1 when
+	// unbounded. For Byte, Bytes and the fixed width integers they are both the number of bytes matched.
+	Min, Max int
+	// Trailing is whether a repetition matches a separator after its last item
+	Trailing Trailing
+	// FoldCase is set when ExactFold or OneOf match regardless of case. Exact also does inside of FoldCase.
+	FoldCase bool
+	// StringOptions is the dialect of a StringLit or StringLitWith
+	StringOptions StringOptions
+
+	parsers   []Parser
+	separator Parser
+	contains  func(r rune) bool
+	ranges    func() [][2]rune
+	// starts are more bytes StringLitWith and NumberLitWith can start with, eg raw string prefixes or the i of inf
+	starts string
+
+	describeChildren sync.Once
+	children         []*Grammar
+	sep              *Grammar
+
+	// callers is the stack the parser was made on, see info
+	callers  []uintptr
+	infoOnce sync.Once
+	pinfo    *parserInfo
+}
+
+// Children returns the Grammar of each parser given to Seq, Any, a repetition like ZeroOrMore, Maybe, NoAutoWS,
+// FoldCase, Capture or Take
+func (g *Grammar) Children() []*Grammar {
+	g.describe()
+	return g.children
+}
+
+// Separator returns the Grammar of the separator given to a repetition like ZeroOrMore, or nil if there wasnt one
+func (g *Grammar) Separator() *Grammar {
+	g.describe()
+	return g.sep
+}
+
+// Contains reports whether r is one of the characters matched by Chars, or stopped at by NotChars
+func (g *Grammar) Contains(r rune) bool {
+	return g.contains != nil && g.contains(r)
+}
+
+// Ranges returns sorted, non overlapping ranges of runes that hold every character Contains is true for, so they can be listed
+// without checking all of unicode. Not every rune in a range has to be in the class. It is nil for anything but
+// Chars and NotChars, and when the class holds most of unicode, eg \P{L}.
+func (g *Grammar) Ranges() [][2]rune {
+	if g.ranges == nil {
+		return nil
+	}
+	return g.ranges()
+}
+
+func (g *Grammar) describe() {
+	g.describeChildren.Do(func() {
+		for _, p := range g.parsers {
+			g.children = append(g.children, Describe(p))
+		}
+		if g.separator != nil {
+			g.sep = Describe(g.separator)
+		}
+	})
+}
+
+// grammarFunc describes a parser. It is only called the first time the Grammar is needed, so parsers made while
+// parsing, eg by Chain, dont pay for describing themselves.
+type grammarFunc func() *Grammar
+
+// opaqueGrammar describes a parser that cant be looked into by its name
+func opaqueGrammar(name string) grammarFunc {
+	return func() *Grammar { return &Grammar{Name: name} }
+}
+
+// site is the stack a parser was made on, it only needs to reach the code that made the parser
+type site [16]uintptr
+
+func (s site) callers() []uintptr {
+	n := 0
+	for n < len(s) && s[n] != 0 {
+		n++
+	}
+	return append([]uintptr{}, s[:n]...)
+}
+
+// parsers knows the grammar func of every parser made by newParser, and where it was made, and what every parser
+// made by wrapParser wraps. Parsers and grammar funcs are known by the address of their closure, so knowing about a
+// parser doesnt keep it alive. It is forgotten when it is collected.
+var parsers = struct {
+	sync.Mutex
+	// grammars is the grammar func of each parser
+	grammars map[uintptr]uintptr
+	// sites is where the parser each grammar func describes was made
+	sites map[uintptr]site
+	// wrappers is what each wrapper wraps
+	wrappers map[uintptr]wrapped
+}{grammars: map[uintptr]uintptr{}, sites: map[uintptr]site{}, wrappers: map[uintptr]wrapped{}}
+
+// wrapped is the address of the Parser a wrapper runs, or of the *Parser when ref is set
+type wrapped struct {
+	parser uintptr
+	ref    bool
+}
+
+// grammars holds the Grammar of each grammar func that has been needed
+var grammars sync.Map
+
+// newParser is NewParser for the built in parsers, which describe themselves with g and start with
+//
+//	if ps.Tracer != nil {
+//		defer g.enter(ps, node).leave()
+//	}
+//
+// so they only cost that check when nothing is tracing or covering them. g has to use a variable, so each parser
+// has a grammar func of its own.
+func newParser(g grammarFunc, p Parser) Parser {
+	var made site
+	runtime.Callers(2, made[:])
+
+	parser, grammar := closure(p), closure(g)
+	parsers.Lock()
+	parsers.grammars[uintptr(parser)] = uintptr(grammar)
+	parsers.sites[uintptr(grammar)] = made
+	parsers.Unlock()
+	runtime.SetFinalizer((*byte)(parser), forgetParser)
+	return p
+}
+
+// wrapParser remembers that p only post processes the result of inner, like Map does, so it is described by
+// inner. p has to use inner.
+func wrapParser(inner Parser, p Parser) Parser {
+	return wrap(p, wrapped{parser: uintptr(closure(inner))})
+}
+
+// refParser is wrapParser for a parser that runs whatever ref points to
+func refParser(ref *Parser, p Parser) Parser {
+	return wrap(p, wrapped{parser: uintptr(unsafe.Pointer(ref)), ref: true})
+}
+
+func wrap(p Parser, w wrapped) Parser {
+	parser := closure(p)
+	parsers.Lock()
+	parsers.wrappers[uintptr(parser)] = w
+	parsers.Unlock()
+	runtime.SetFinalizer((*byte)(parser), forgetParser)
+	return p
+}
+
+func forgetParser(parser *byte) {
+	key := uintptr(unsafe.Pointer(parser))
+	parsers.Lock()
+	grammar, ok := parsers.grammars[key]
+	delete(parsers.grammars, key)
+	delete(parsers.sites, grammar)
+	delete(parsers.wrappers, key)
+	parsers.Unlock()
+	if ok {
+		grammars.Delete(grammar)
+	}
+}
+
+// closure returns the address of the closure f points to
+func closure[F Parser | grammarFunc](f F) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&f))
+}
+
+// grammar returns the Grammar g describes, making it the first time it is needed
+func (g grammarFunc) grammar() *Grammar {
+	key := uintptr(closure(g))
+	if described, ok := grammars.Load(key); ok {
+		return described.(*Grammar)
+	}
+
+	described := g()
+	parsers.Lock()
+	described.callers = parsers.sites[key].callers()
+	parsers.Unlock()
+
+	actual, _ := grammars.LoadOrStore(key, described)
+	return actual.(*Grammar)
+}
+
+// Describe returns the Grammar of a parser. Parsers that only post process a result, like Map and Bind,
+// are described by the parser they wrap. Custom parsers are GrammarOpaque, they only have a Name when they were
+// made with NewParser.
+//
+// Describing a grammar never runs any of its parsers.
+func Describe(p Parserish) *Grammar {
+	parser := Parsify(p)
+	key := uintptr(closure(parser))
+	parsers.Lock()
+	grammar, own := parsers.grammars[key]
+	w, wraps := parsers.wrappers[key]
+	parsers.Unlock()
+
+	described := &Grammar{Kind: GrammarOpaque}
+	switch {
+	case own:
+		described = (*(*grammarFunc)(unsafe.Pointer(&grammar))).grammar()
+	case wraps && w.ref:
+		described = Describe(**(**Parser)(unsafe.Pointer(&w.parser)))
+	case wraps:
+		described = Describe(*(*Parser)(unsafe.Pointer(&w.parser)))
+	}
+	// what parser was made with only lives as long as it does
+	runtime.KeepAlive(parser)
+	return described
+}
+
+// NewParser should be called around the creation of every Parser. It names the parser and remembers where it was
+// made, so it can be described, traced and covered. When nothing is tracing or covering it, it costs a single check
+// per call.
+func NewParser(description string, p Parser) Parser {
+	g := opaqueGrammar(description)
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		p(ps, node)
+	})
+}
@@ -0,0 +1,83 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	t.Run("literals", func(t *testing.T) {
+		require.Equal(t, GrammarExact, Describe("hello").Kind)
+		require.Equal(t, "hello", Describe("hello").Literal)
+		require.Equal(t, "a-z", Describe(Chars("a-z", 2, 4)).Literal)
+		require.Equal(t, 2, Describe(Chars("a-z", 2, 4)).Min)
+		require.Equal(t, 4, Describe(Chars("a-z", 2, 4)).Max)
+		require.Equal(t, GrammarNotChars, Describe(NotChars("<>")).Kind)
+		require.Equal(t, "[0-9]+", Describe(Regex("[0-9]+")).Literal)
+		require.Equal(t, `"'`, Describe(StringLit(`"'`)).Literal)
+		require.Equal(t, GrammarNumberLit, Describe(NumberLit()).Kind)
+		require.Equal(t, []string{"*/"}, Describe(Until("*/")).Terminators)
+	})
+
+	t.Run("combinators", func(t *testing.T) {
+		g := Describe(Seq("a", Any("b", "c"), ZeroOrMore("d", ","), Maybe("e"), NoAutoWS("f")))
+		require.Equal(t, GrammarSeq, g.Kind)
+
+		children := g.Children()
+		require.Len(t, children, 5)
+		require.Equal(t, GrammarAny, children[1].Kind)
+		require.Equal(t, "c", children[1].Children()[1].Literal)
+		require.Equal(t, GrammarMany, children[2].Kind)
+		require.Equal(t, 0, children[2].Min)
+		require.Equal(t, ",", children[2].Separator().Literal)
+		require.Equal(t, GrammarMaybe, children[3].Kind)
+		require.Equal(t, GrammarNoAutoWS, children[4].Kind)
+	})
+
+	t.Run("wrappers are transparent", func(t *testing.T) {
+		g := Describe(Map(Bind("a", 1), func(n *Result) { panic("should not be called") }))
+		require.Equal(t, GrammarExact, g.Kind)
+		require.Equal(t, GrammarExact, Describe(Named("label", WithKind("kind", "a"))).Kind)
+	})
+
+	t.Run("doesnt run any parser", func(t *testing.T) {
+		calls := 0
+		g := Describe(Instrument(MapState(Chain("a", func(n *Result) Parserish {
+			calls++
+			return "b"
+		}), func(ps *State, n *Result) { calls++ }), NewTracer(nil)))
+		require.Equal(t, "Chain()", g.Name)
+		require.Equal(t, 0, calls)
+
+		g = Describe(func(ps *State, node *Result) { panic("should not be called") })
+		require.Equal(t, GrammarOpaque, g.Kind)
+	})
+
+	t.Run("recursion", func(t *testing.T) {
+		var group Parser
+		group = Seq("(", Maybe(&group), ")")
+
+		g := Describe(&group)
+		require.Same(t, g, g.Children()[1].Children()[0])
+	})
+
+	t.Run("opaque", func(t *testing.T) {
+		require.Equal(t, GrammarOpaque, Describe(func(ps *State, node *Result) {}).Kind)
+		custom := Describe(NewParser("custom", func(ps *State, node *Result) {}))
+		require.Equal(t, GrammarOpaque, custom.Kind)
+		require.Equal(t, "custom", custom.Name)
+	})
+
+	t.Run("chars", func(t *testing.T) {
+		g := Describe(Chars(`a-c\-x`))
+		require.True(t, g.Contains('b'))
+		require.True(t, g.Contains('-'))
+		require.True(t, g.Contains('x'))
+		require.False(t, g.Contains('d'))
+		require.Equal(t, [][2]rune{{'-', '-'}, {'a', 'c'}, {'x', 'x'}}, g.Ranges())
+
+		require.Equal(t, [][2]rune{{'0', '9'}, {'Ͱ', 'ͳ'}}, Describe(Chars(`0-9\p{Greek}`)).Ranges()[:2])
+		require.Nil(t, Describe(Chars(`\P{Greek}`)).Ranges())
+	})
+}
@@ -29,28 +29,27 @@ var (
 		n.Result = ret
 	})
 
-	attr  = Seq(identifier, "=", StringLit(`"'`))
-	attrs = ZeroOrMore(attr).Map(func(node *Result) {
+	attr  = Seq(Named("name", identifier), "=", Named("value", StringLit(`"'`)))
+	attrs = ZeroOrMore(Named("attr", attr)).Map(func(node *Result) {
 		attr := map[string]string{}
 
-		for _, attrNode := range node.Child {
-			attr[attrNode.Child[0].Token] = attrNode.Child[2].Token
+		for _, attrNode := range node.Query("attr") {
+			attr[attrNode.First("name").Token] = attrNode.First("value").Token
 		}
 
 		node.Result = attr
 	})
 
-	tstart = Seq("<", identifier, Cut(), attrs, ">")
+	tstart = Seq("<", Named("name", identifier), Cut(), Named("attrs", attrs), ">")
 	tend   = Seq("</", Cut(), identifier, ">")
 )
 
 func init() {
-	tag = Seq(tstart, Cut(), elements, tend).Map(func(node *Result) {
-		openTag := node.Child[0]
+	tag = Seq(Named("start", tstart), Cut(), Named("body", elements), tend).Map(func(node *Result) {
 		node.Result = htmlTag{
-			Name:       openTag.Child[1].Token,
-			Attributes: openTag.Child[3].Result.(map[string]string),
-			Body:       node.Child[2].Result.([]interface{}),
+			Name:       node.First("start/name").Token,
+			Attributes: node.First("start/attrs").Result.(map[string]string),
+			Body:       node.First("body").Result.([]interface{}),
 		}
 	})
 }
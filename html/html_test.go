@@ -1,10 +1,13 @@
 package html
 
 import (
+	"errors"
+	"math/rand"
 	"os"
 	"testing"
 
 	"github.com/ajitid/goparsify"
+	"github.com/ajitid/goparsify/generate"
 	"github.com/stretchr/testify/require"
 )
 
@@ -17,3 +20,25 @@ func TestParse(t *testing.T) {
 		htmlTag{Name: "p", Attributes: map[string]string{"color": "blue"}, Body: []interface{}{"world"}},
 	}}, result)
 }
+
+func FuzzParse(f *testing.F) {
+	gen := generate.New(&tag, generate.Options{MaxDepth: 6})
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		f.Add(gen.Generate(r))
+		f.Add(gen.GenerateInvalid(r))
+	}
+	f.Add(`<body>hello <p color="blue">world</p></body>`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		result, err := parse(input)
+		var perr *goparsify.Error
+		if errors.As(err, &perr) {
+			require.True(t, perr.Pos() >= 0 && perr.Pos() <= len(input))
+			require.NotPanics(t, func() { perr.LocateError(input) })
+		}
+		if err == nil {
+			require.IsType(t, htmlTag{}, result)
+		}
+	})
+}
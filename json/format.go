@@ -0,0 +1,48 @@
+package json
+
+import (
+	"bytes"
+	stdlibJson "encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/ajitid/goparsify"
+)
+
+// Format parses a json document and prints it back with every array element and object member on its own line,
+// indented with indent
+func Format(input string, indent string) (string, error) {
+	result, err := Parse(_value, input, ASCIIWhitespace)
+	if err != nil {
+		return "", err
+	}
+	return formatter(indent).Print(result), nil
+}
+
+func formatter(indent string) *Printer {
+	list := Hint{Separator: ",", Between: LayoutLine, Indent: true}
+	return &Printer{
+		Indent: indent,
+		Hints: map[string]Hint{
+			"members":  list,
+			"elements": list,
+			"colon":    {After: LayoutSpace},
+		},
+		Tokens: map[string]func(n *Result) string{
+			"string": func(n *Result) string {
+				buf := &bytes.Buffer{}
+				enc := stdlibJson.NewEncoder(buf)
+				enc.SetEscapeHTML(false)
+				_ = enc.Encode(n.Token)
+				return strings.TrimSuffix(buf.String(), "\n")
+			},
+			"number": func(n *Result) string {
+				// keep numbers as they were written, 1.50 is still 1.50
+				if n.Input != "" {
+					return n.Input[n.Start:n.End]
+				}
+				return fmt.Sprint(n.Result)
+			},
+		},
+	}
+}
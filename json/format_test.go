@@ -0,0 +1,50 @@
+package json
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ajitid/goparsify"
+	"github.com/ajitid/goparsify/generate"
+	"github.com/ajitid/goparsify/parsetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	t.Run("indents", func(t *testing.T) {
+		result, err := Format(`{"a": [1, 2.50, {}], "b" :"<é\n>", "c": [], "d": {"e": null}}`, "  ")
+		require.NoError(t, err)
+		require.Equal(t, `{
+  "a": [
+    1,
+    2.50,
+    {}
+  ],
+  "b": "<é\n>",
+  "c": [],
+  "d": {
+    "e": null
+  }
+}`, result)
+	})
+
+	t.Run("scalars", func(t *testing.T) {
+		result, err := Format(` true `, "\t")
+		require.NoError(t, err)
+		require.Equal(t, "true", result)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := Format(`[1,`, "\t")
+		require.Error(t, err)
+	})
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	gen := generate.New(_value, generate.Options{MaxDepth: 6, MaxSize: 512})
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		parsetest.RoundTrip(t, _value, formatter("\t"), gen.Generate(r), goparsify.ASCIIWhitespace)
+	}
+}
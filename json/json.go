@@ -9,11 +9,12 @@ var (
 	_null       = Bind("null", nil)
 	_true       = Bind("true", true)
 	_false      = Bind("false", false)
-	_string     = Map(StringLit(`"`), func(r *Result) { r.Result = r.Token })
-	_number     = NumberLit()
-	_properties = ZeroOrMore(Seq(StringLit(`"`), ":", &_value), ",")
+	_stringLit  = StringLitWith(StringOptions{Quotes: `"`, ControlEscapes: true})
+	_string     = WithKind("string", Map(_stringLit, func(r *Result) { r.Result = r.Token }))
+	_number     = WithKind("number", NumberLitWith(NumberOptions{OverflowFloats: true}))
+	_properties = WithKind("members", SepBy(Seq(WithKind("string", _stringLit), WithKind("colon", ":"), Cut(), &_value), ","))
 
-	_array = Seq("[", Cut(), ZeroOrMore(&_value, ","), "]").Map(func(n *Result) {
+	_array = Seq("[", Cut(), WithKind("elements", SepBy(&_value, ",")), "]").Map(func(n *Result) {
 		ret := []interface{}{}
 		for _, child := range n.Child[2].Child {
 			ret = append(ret, child.Result)
@@ -25,18 +26,22 @@ var (
 		ret := map[string]interface{}{}
 
 		for _, prop := range n.Child[2].Child {
-			ret[prop.Child[0].Token] = prop.Child[2].Result
+			ret[prop.Child[0].Token] = prop.Child[3].Result
 		}
 
 		n.Result = ret
 	})
 )
 
+// _unmarshal reuses results between calls, which is safe as the Map callbacks copy everything they keep
+var _unmarshal *Reusable
+
 func init() {
 	_value = Any(_null, _true, _false, _string, _number, _array, _object)
+	_unmarshal = _value.Reuse(ASCIIWhitespace)
 }
 
 // Unmarshall json string into map[string]interface{} or []interface{}
 func Unmarshal(input string) (interface{}, error) {
-	return Run(_value, input, ASCIIWhitespace)
+	return _unmarshal.Run(input)
 }
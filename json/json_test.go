@@ -2,11 +2,15 @@ package json
 
 import (
 	stdlibJson "encoding/json"
+	"errors"
+	"math/rand"
 	"testing"
 
 	"os"
 
 	"github.com/ajitid/goparsify"
+	"github.com/ajitid/goparsify/generate"
+	"github.com/ajitid/goparsify/parsetest"
 	parsecJson "github.com/prataprc/goparsec/json"
 	"github.com/stretchr/testify/require"
 )
@@ -41,6 +45,86 @@ func TestUnmarshal(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, map[string]interface{}{"true": true, "false": false, "null": nil, "number": int64(404)}, result)
 	})
+
+	t.Run("trailing commas", func(t *testing.T) {
+		_, err := Unmarshal(`[1,2,]`)
+		require.Equal(t, `offset 4: unexpected trailing separator`, err.Error())
+
+		_, err = Unmarshal(`{"a": 1,}`)
+		require.Equal(t, `offset 7: unexpected trailing separator`, err.Error())
+
+		_, err = Unmarshal(`[,]`)
+		require.Equal(t, `offset 1: expected null or true or false or " or number or ]`, err.Error())
+	})
+}
+
+func TestGolden(t *testing.T) {
+	parsetest.RunDir(t, _value, "testdata/golden", parsetest.Options{Format: parsetest.SExpr, WS: goparsify.ASCIIWhitespace})
+}
+
+func TestUnmarshalGenerated(t *testing.T) {
+	gen := generate.New(&_value, generate.Options{MaxDepth: 8})
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 500; i++ {
+		input := gen.Generate(r)
+
+		var expected interface{}
+		require.NoError(t, stdlibJson.Unmarshal([]byte(input), &expected), input)
+
+		result, err := Unmarshal(input)
+		require.NoError(t, err, input)
+		require.Equal(t, expected, normalizeNumbers(result), input)
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	gen := generate.New(&_value, generate.Options{MaxDepth: 6})
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		f.Add(gen.Generate(r))
+		f.Add(gen.GenerateInvalid(r))
+	}
+	f.Add(benchmarkString)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		result, err := Unmarshal(input)
+		if err != nil {
+			var perr *goparsify.Error
+			if errors.As(err, &perr) {
+				require.True(t, perr.Pos() >= 0 && perr.Pos() <= len(input))
+				require.NotPanics(t, func() { perr.LocateError(input) })
+			}
+			return
+		}
+
+		// anything we accept should survive a round trip through encoding/json
+		encoded, err := stdlibJson.Marshal(normalizeNumbers(result))
+		if err == nil {
+			var expected interface{}
+			require.NoError(t, stdlibJson.Unmarshal(encoded, &expected))
+			reparsed, err := Unmarshal(string(encoded))
+			require.NoError(t, err, string(encoded))
+			require.Equal(t, expected, normalizeNumbers(reparsed))
+		}
+	})
+}
+
+// normalizeNumbers turns int64s into float64s like encoding/json does
+func normalizeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = normalizeNumbers(v[k])
+		}
+	}
+	return v
 }
 
 func BenchmarkUnmarshalParsec(b *testing.B) {
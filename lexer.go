@@ -0,0 +1,236 @@
+package goparsify
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Token is a piece of input matched by one of the rules of a Lexer
+type Token struct {
+	// Kind is the Kind of the rule that matched it
+	Kind string
+	// Text is the input it was matched from, and Value the .Result of the rule that matched it, eg the number
+	// for a NumberLit. If it has no .Result but its .Token isnt just Text, eg the unescaped text of a StringLit,
+	// Value is the .Token.
+	Text  string
+	Value interface{}
+	// Start and End are where it is in the input
+	Start, End int
+}
+
+// TokenRule is how a Lexer matches one kind of token
+type TokenRule struct {
+	Kind   string
+	Parser Parserish
+	// Skip drops what it matches, eg for whitespace and comments
+	Skip bool
+	// Priority decides between rules that match the same length, eg to make if a keyword rather than an
+	// identifier. Higher wins, then whichever rule was given first.
+	Priority int
+	// Error makes this the rule for input no other rule matches, so Lex carries on instead of failing. Its Parser
+	// decides where to start again, eg NotChars(" \n") to skip to the next space. If it doesnt match anything
+	// the token is a single character. Parsers can then recover from the error tokens with Tok.
+	Error bool
+}
+
+// Lexer splits input into tokens for the Tok parser, so a grammar can be written in terms of tokens and
+// backtracking doesnt have to skip whitespace or scan the same characters again. It is safe to use from
+// multiple goroutines.
+//
+// Lexing is a whole pass over the input before parsing starts, so it is only quicker when the grammar backtracks
+// a lot over the same tokens or skips a lot of comments. Small grammars that rarely backtrack are quicker parsed
+// straight from the input, compare go test -bench Statements.
+type Lexer struct {
+	rules   []TokenRule
+	parsers []Parser
+	// errorRule is the first rule with Error set, or -1
+	errorRule int
+	// dispatch says which rules can start with each byte, or is nil if that cant be worked out. Like for Any it
+	// waits for the first use, as rules may be pointers that are only set in init.
+	dispatchOnce sync.Once
+	dispatch     *dispatchTable
+}
+
+// NewLexer returns a Lexer that matches the longest token any of the rules can at each point of the input. The
+// rules are run without skipping whitespace, so it has to be matched by a rule too.
+func NewLexer(rules ...TokenRule) *Lexer {
+	l := &Lexer{rules: rules, parsers: make([]Parser, len(rules)), errorRule: -1}
+	for i, rule := range rules {
+		l.parsers[i] = Parsify(rule.Parser)
+		if rule.Error && l.errorRule < 0 {
+			l.errorRule = i
+		}
+	}
+	return l
+}
+
+// Lex splits input into tokens, leaving out the ones matched by a Skip rule. It is an error if nothing matches
+// at some point, or only matches nothing, unless there is an Error rule.
+func (l *Lexer) Lex(input string) ([]Token, error) {
+	l.dispatchOnce.Do(func() {
+		grammars := make([]*Grammar, len(l.parsers))
+		for i, parser := range l.parsers {
+			grammars[i] = Describe(parser)
+		}
+		l.dispatch = newDispatchTable(grammars, false)
+	})
+
+	ps := NewState(input)
+	ps.WS = NoWhitespace
+
+	// tokens are usually a few bytes long, so this is enough to not have to grow much
+	tokens := make([]Token, 0, len(input)/8+1)
+	node := &Result{}
+	for pos := 0; pos < len(input); {
+		viable := ^uint64(0)
+		if l.dispatch != nil {
+			viable = l.dispatch.viable[input[pos]]
+		}
+
+		best, end := -1, pos
+		var bestToken string
+		var bestValue interface{}
+		for i, parser := range l.parsers {
+			if i < 64 && viable&(1<<uint(i)) == 0 || i == l.errorRule {
+				continue
+			}
+			ps.Pos, ps.Cut, ps.Error = pos, 0, Error{}
+			ps.arena.reset(arenaMark{})
+			*node = Result{Input: input}
+			parser(ps, node)
+			if ps.Errored() {
+				continue
+			}
+			if ps.Pos > end || (ps.Pos == end && best >= 0 && l.rules[i].Priority > l.rules[best].Priority) {
+				best, end, bestToken, bestValue = i, ps.Pos, node.Token, node.Result
+			}
+		}
+
+		if best < 0 {
+			if l.errorRule < 0 {
+				return tokens, &Error{pos: pos, expected: "token"}
+			}
+			best, end, bestToken, bestValue = l.errorRule, l.resync(ps, node, pos), "", nil
+			if !ps.Errored() {
+				bestToken, bestValue = node.Token, node.Result
+			}
+		}
+		if !l.rules[best].Skip {
+			tok := Token{Kind: l.rules[best].Kind, Text: input[pos:end], Value: bestValue, Start: pos, End: end}
+			if tok.Value == nil && bestToken != "" && bestToken != tok.Text {
+				tok.Value = bestToken
+			}
+			tokens = append(tokens, tok)
+		}
+		pos = end
+	}
+	return tokens, nil
+}
+
+// resync runs the Error rule at pos and returns where lexing should start again
+func (l *Lexer) resync(ps *State, node *Result, pos int) int {
+	ps.Pos, ps.Cut, ps.Error = pos, 0, Error{}
+	ps.arena.reset(arenaMark{})
+	*node = Result{Input: ps.Input}
+	l.parsers[l.errorRule](ps, node)
+	if ps.Errored() || ps.Pos <= pos {
+		ps.ErrorHere("token")
+		_, size := utf8.DecodeRuneInString(ps.Input[pos:])
+		return pos + size
+	}
+	return ps.Pos
+}
+
+// Run lexes input then runs parser over the tokens, failing if any are left over. parser should be built from Tok
+// rather than parsers that match the input directly.
+func (l *Lexer) Run(parser Parserish, input string) (result interface{}, err error) {
+	ret, err := l.Parse(parser, input)
+	return ret.Result, err
+}
+
+// Parse is Run, but returns the whole Result tree instead of just its .Result
+func (l *Lexer) Parse(parser Parserish, input string) (*Result, error) {
+	ret := NewResult(input)
+	tokens, err := l.Lex(input)
+	if err != nil {
+		return ret, err
+	}
+
+	ps := NewState(input)
+	ps.WS = NoWhitespace
+	ps.tokens = tokens
+	// the parse starts at the first token, after anything that was skipped
+	ps.Pos = len(input)
+	if len(tokens) > 0 {
+		ps.Pos = tokens[0].Start
+	}
+
+	Parsify(parser)(ps, ret)
+	if ps.Errored() || ps.Pos < len(input) {
+		return ret, ps.parseError()
+	}
+	return ret, nil
+}
+
+// Tok matches the next token from a Lexer if it is of the given kind, and if any texts are given, one of them.
+// .Token is the text of the token, .Result its value and .Kind its kind. See Lexer.Run.
+func Tok(kind string, texts ...string) Parser {
+	expected := kind
+	if len(texts) > 0 {
+		quoted := make([]string, len(texts))
+		for i, text := range texts {
+			quoted[i] = strconv.Quote(text)
+		}
+		expected = strings.Join(quoted, " or ")
+	}
+
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarToken, Name: "Tok(" + kind + ")", Literal: kind, Literals: texts}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		i, ok := ps.tokenAt()
+		if !ok || ps.tokens[i].Kind != kind || (len(texts) > 0 && !containsString(texts, ps.tokens[i].Text)) {
+			ps.ErrorHere(expected)
+			return
+		}
+
+		tok := ps.tokens[i]
+		node.Token = tok.Text
+		node.Result = tok.Value
+		node.Kind = kind
+		node.Start = tok.Start
+		node.End = tok.End
+
+		// skip straight to the next token, past anything the lexer skipped
+		ps.tokenHint = i + 1
+		ps.Pos = len(ps.Input)
+		if i+1 < len(ps.tokens) {
+			ps.Pos = ps.tokens[i+1].Start
+		}
+	})
+}
+
+// tokenAt returns the index of the token starting at Pos. Tokens are nearly always matched in order, so the one
+// after the last match is checked before searching for it.
+func (s *State) tokenAt() (int, bool) {
+	if i := s.tokenHint; i < len(s.tokens) && s.tokens[i].Start == s.Pos {
+		return i, true
+	}
+	i := sort.Search(len(s.tokens), func(i int) bool { return s.tokens[i].Start >= s.Pos })
+	return i, i < len(s.tokens) && s.tokens[i].Start == s.Pos
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testLexer = NewLexer(
+	TokenRule{Kind: "space", Parser: Chars(" \t\n"), Skip: true},
+	TokenRule{Kind: "comment", Parser: Regex(`//[^\n]*`), Skip: true},
+	TokenRule{Kind: "ident", Parser: Chars("a-zA-Z_")},
+	TokenRule{Kind: "keyword", Parser: OneOf("let", "if", "else"), Priority: 1},
+	TokenRule{Kind: "number", Parser: NumberLitWith(NumberOptions{Unsigned: true})},
+	TokenRule{Kind: "string", Parser: StringLitWith(StringOptions{Quotes: `"`, ControlEscapes: true})},
+	TokenRule{Kind: "op", Parser: OneOf("+", "-", "*", "=", "==", "(", ")", ";")},
+)
+
+func kindsAndTexts(tokens []Token) []string {
+	ret := []string{}
+	for _, tok := range tokens {
+		ret = append(ret, tok.Kind+":"+tok.Text)
+	}
+	return ret
+}
+
+func TestLexer(t *testing.T) {
+	t.Run("tokens", func(t *testing.T) {
+		tokens, err := testLexer.Lex(`let x = "a\tb" // comment` + "\n  if iffy == 12")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"keyword:let", "ident:x", "op:=", `string:"a\tb"`, "keyword:if", "ident:iffy", "op:==", "number:12",
+		}, kindsAndTexts(tokens))
+
+		require.Equal(t, 8, tokens[3].Start)
+		require.Equal(t, 14, tokens[3].End)
+		require.Equal(t, "a\tb", tokens[3].Value)
+		require.Equal(t, int64(12), tokens[7].Value)
+	})
+
+	t.Run("priority only breaks ties", func(t *testing.T) {
+		tokens, err := testLexer.Lex("letter let")
+		require.NoError(t, err)
+		require.Equal(t, []string{"ident:letter", "keyword:let"}, kindsAndTexts(tokens))
+	})
+
+	t.Run("first rule breaks ties without priority", func(t *testing.T) {
+		lexer := NewLexer(TokenRule{Kind: "a", Parser: Chars("a-z")}, TokenRule{Kind: "b", Parser: Chars("a-c")})
+		tokens, err := lexer.Lex("abc")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a:abc"}, kindsAndTexts(tokens))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		tokens, err := testLexer.Lex(" // nothing")
+		require.NoError(t, err)
+		require.Empty(t, tokens)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		tokens, err := testLexer.Lex("x = @")
+		require.Equal(t, "offset 4: expected token", err.Error())
+		require.Len(t, tokens, 2)
+
+		// rules that match nothing dont count
+		_, err = NewLexer(TokenRule{Kind: "maybe", Parser: Maybe("a")}).Lex("b")
+		require.Equal(t, "offset 0: expected token", err.Error())
+	})
+
+	t.Run("error tokens", func(t *testing.T) {
+		lexer := NewLexer(
+			TokenRule{Kind: "space", Parser: Chars(" "), Skip: true},
+			TokenRule{Kind: "ident", Parser: Chars("a-z")},
+			TokenRule{Kind: "bad", Parser: NotChars(" "), Error: true},
+		)
+		tokens, err := lexer.Lex("x @@y z")
+		require.NoError(t, err)
+		require.Equal(t, []string{"ident:x", "bad:@@y", "ident:z"}, kindsAndTexts(tokens))
+
+		// a single character when the rule doesnt match
+		lexer = NewLexer(
+			TokenRule{Kind: "ident", Parser: Chars("a-z")},
+			TokenRule{Kind: "bad", Parser: "never", Error: true},
+		)
+		tokens, err = lexer.Lex("aéb")
+		require.NoError(t, err)
+		require.Equal(t, []string{"ident:a", "bad:é", "ident:b"}, kindsAndTexts(tokens))
+
+		// which the grammar can skip over
+		words := ZeroOrMore(Any(Tok("ident"), Map(Tok("bad"), func(n *Result) { n.Result = "?" })))
+		result, err := lexer.Parse(words, "aéb")
+		require.NoError(t, err)
+		require.Equal(t, "?", result.Child[1].Result)
+	})
+}
+
+func TestTok(t *testing.T) {
+	var expr Parser
+	atom := Any(Tok("number"), Tok("ident"), Tok("string"), Seq(Tok("op", "("), &expr, Tok("op", ")")))
+	expr = Seq(atom, ZeroOrMore(Seq(Tok("op", "+", "-", "*"), atom)))
+	let := Seq(Tok("keyword", "let"), Cut(), Tok("ident"), Tok("op", "="), &expr, Tok("op", ";"))
+	program := ZeroOrMore(Any(let, Seq(&expr, Tok("op", ";"))))
+
+	t.Run("parses tokens", func(t *testing.T) {
+		result, err := testLexer.Parse(program, "let x = (1 + y) * 2; // x\nx - 1;")
+		require.NoError(t, err)
+		require.Len(t, result.Child, 2)
+
+		x := result.Child[0].Child[2]
+		require.Equal(t, "x", x.Token)
+		require.Equal(t, "ident", x.Kind)
+		require.Equal(t, 4, x.Start)
+		require.Equal(t, 5, x.End)
+
+		one := result.Child[0].Child[4].Child[0].Child[1].Child[0]
+		require.Equal(t, int64(1), one.Result)
+	})
+
+	t.Run("trivia around tokens", func(t *testing.T) {
+		_, err := testLexer.Run(program, "  x;  // done\n")
+		require.NoError(t, err)
+
+		_, err = testLexer.Run(program, "")
+		require.NoError(t, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := testLexer.Run(program, "let x = ;")
+		require.Equal(t, `offset 8: expected number or ident or string or "("`, err.Error())
+
+		_, err = testLexer.Run(program, "let 1 = 2;")
+		require.Equal(t, "offset 4: expected ident", err.Error())
+
+		_, err = testLexer.Run(program, "x; )")
+		require.Equal(t, "left unparsed: )", err.Error())
+
+		_, err = testLexer.Run(program, "x = #")
+		require.Equal(t, "offset 4: expected token", err.Error())
+	})
+
+	t.Run("backtracks over tokens", func(t *testing.T) {
+		call := Seq(Tok("ident"), Tok("op", "("), Tok("op", ")"))
+		assign := Seq(Tok("ident"), Tok("op", "="), Tok("number"))
+		result, err := testLexer.Parse(Any(call, assign), "f = 1")
+		require.NoError(t, err)
+		require.Equal(t, "1", result.Child[2].Token)
+	})
+
+	t.Run("needs a lexer", func(t *testing.T) {
+		_, p := runParser("x", Tok("ident"))
+		require.Equal(t, "offset 0: expected ident", p.Error.Error())
+	})
+}
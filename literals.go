@@ -1,152 +1,532 @@
 package goparsify
 
 import (
-	"bytes"
+	"errors"
+	"math"
+	"math/big"
 	"strconv"
+	"strings"
+	"unicode/utf16"
 	"unicode/utf8"
 )
 
 // StringLit matches a quoted string and returns it in .Token. It may contain:
 //  - unicode
-//  - escaped characters, eg \" or \n
+//  - escaped characters, which stand for themselves, eg \"
 //  - unicode sequences, eg \uBEEF
 func StringLit(allowedQuotes string) Parser {
-	return NewParser("string literal", func(ps *State, node *Result) {
+	return StringLitWith(StringOptions{Quotes: allowedQuotes})
+}
+
+// StringOptions are the dialect of string literal StringLitWith matches. The zero value, apart from Quotes, is
+// StringLit: \uBEEF escapes, and any other escaped character standing for itself.
+type StringOptions struct {
+	// Quotes are the characters a string can be quoted with, eg `"'`
+	Quotes string
+	// ControlEscapes replaces \a \b \f \n \r \t and \v with the control characters they stand for, like in C and
+	// json. Without it they are just the escaped letter.
+	ControlEscapes bool
+	// Hex allows \xHH escapes, for the character U+00HH like in javascript and python
+	Hex bool
+	// BracedUnicode allows \u{1F600} escapes of 1 to 6 hex digits, like in javascript and rust
+	BracedUnicode bool
+	// Surrogates joins \uD83D\uDE00 escaped UTF-16 surrogate pairs into one character, like in json. Lone
+	// surrogates become U+FFFD, or are errors when Strict.
+	Surrogates bool
+	// Raw strings have no escapes, backslashes are just backslashes, like go backtick strings
+	Raw bool
+	// RawPrefixes are the characters that make a string raw when they come right before the quote, eg "rR" for
+	// python r"C:\path"
+	RawPrefixes string
+	// DoubledQuotes lets a quote be written twice to put it in the string, eg 'it''s' like in SQL and CSV
+	DoubledQuotes bool
+	// TripleQuotes allows strings started and ended by three quotes, which may span lines, like in python
+	TripleQuotes bool
+	// Strict rejects escapes that arent known, and control characters that arent escaped, like json does.
+	// Triple quoted strings may still contain newlines and tabs.
+	Strict bool
+	// Escapes are the characters Strict allows after a backslash, apart from the u and x escapes above. When empty
+	// it is the escapes of C and json, abfnrtv\/"' and the quotes. Use JSONEscapes for json.
+	Escapes string
+}
+
+// JSONEscapes are the only escapes json allows, apart from \u
+const JSONEscapes = `"\/bfnrt`
+
+// StringLitWith is StringLit for other dialects of string literals. .Token is the string once its escapes
+// have been replaced, without its quotes.
+func StringLitWith(opts StringOptions) Parser {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarStringLit, Name: "string literal", Literal: opts.Quotes, StringOptions: opts, starts: opts.RawPrefixes}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		startpos := ps.Pos
 		ps.WS(ps)
 
-		if !stringContainsByte(allowedQuotes, ps.Input[ps.Pos]) {
-			ps.ErrorHere(allowedQuotes)
+		lit, err := opts.scan(ps.Input, ps.Pos)
+		if err.expected != "" {
+			ps.Error = err
+			ps.Pos = startpos
 			return
 		}
-		quote := ps.Input[ps.Pos]
 
-		var end = ps.Pos + 1
+		node.Token = lit.token
+		node.Start = lit.start
+		node.End = lit.end
+		ps.Pos = lit.next
+	})
+}
 
-		inputLen := len(ps.Input)
-		var buf *bytes.Buffer
+type stringMatch struct {
+	token string
+	// start and end are the contents of the string, next is after the closing quote
+	start, end, next int
+}
 
-		for end < inputLen {
-			switch ps.Input[end] {
-			case '\\':
-				if end+1 >= inputLen {
-					ps.ErrorHere(string(quote))
-					return
-				}
+// scan matches the string literal at pos
+func (o *StringOptions) scan(input string, pos int) (lit stringMatch, err Error) {
+	litpos := pos
+	raw := o.Raw
+	if pos+1 < len(input) && stringContainsByte(o.RawPrefixes, input[pos]) && stringContainsByte(o.Quotes, input[pos+1]) {
+		raw = true
+		pos++
+	}
+	if pos >= len(input) || !stringContainsByte(o.Quotes, input[pos]) {
+		return lit, Error{pos: litpos, expected: o.Quotes}
+	}
 
-				if buf == nil {
-					buf = bytes.NewBufferString(ps.Input[ps.Pos+1 : end])
-				}
+	quote := input[pos]
+	closing := input[pos : pos+1]
+	if o.TripleQuotes && pos+2 < len(input) && input[pos+1] == quote && input[pos+2] == quote {
+		closing = input[pos : pos+3]
+	}
+	lit.start = pos + len(closing)
 
-				c := ps.Input[end+1]
-				if c == 'u' {
-					if end+6 >= inputLen {
-						ps.Error.expected = "[a-f0-9]{4}"
-						ps.Error.pos = end + 2
-						return
-					}
+	end := lit.start
+	var buf *strings.Builder
+	for end < len(input) {
+		// skip to the next byte that could mean something, they are all ascii so never part of a multi byte rune
+		span := end
+		for end < len(input) && input[end] != quote && input[end] != '\\' && input[end] >= ' ' {
+			end++
+		}
+		if buf != nil {
+			buf.WriteString(input[span:end])
+		}
+		if end >= len(input) {
+			break
+		}
 
-					r, ok := unhex(ps.Input[end+2 : end+6])
-					if !ok {
-						ps.Error.expected = "[a-f0-9]"
-						ps.Error.pos = end + 2
-						return
-					}
-					buf.WriteRune(r)
-					end += 6
-				} else {
-					buf.WriteByte(c)
-					end += 2
-				}
-			case quote:
+		c := input[end]
+		switch {
+		case c == quote && (len(closing) == 1 || strings.HasPrefix(input[end:], closing)):
+			if o.DoubledQuotes && len(closing) == 1 && end+1 < len(input) && input[end+1] == quote {
 				if buf == nil {
-					node.Start = ps.Pos + 1
-					node.End = end
-					node.Token = ps.Input[ps.Pos+1 : end]
-					ps.Pos = end + 1
-					return
+					buf = &strings.Builder{}
+					buf.WriteString(input[lit.start:end])
 				}
-				node.Token = buf.String()
-				node.Start = ps.Pos
-				node.End = ps.Pos + len(node.Token)
-				ps.Pos = end + 1
-				return
-			default:
-				if buf == nil {
-					if ps.Input[end] < 127 {
-						end++
-					} else {
-						_, w := utf8.DecodeRuneInString(ps.Input[end:])
-						end += w
+				buf.WriteByte(quote)
+				end += 2
+				continue
+			}
+
+			lit.end = end
+			lit.next = end + len(closing)
+			if buf == nil {
+				lit.token = input[lit.start:end]
+			} else {
+				lit.token = buf.String()
+			}
+			return lit, Error{}
+
+		case c == '\\' && !raw:
+			if end+1 >= len(input) {
+				return lit, Error{pos: litpos, expected: closing}
+			}
+			if buf == nil {
+				buf = &strings.Builder{}
+				buf.WriteString(input[lit.start:end])
+			}
+			n, err := o.unescape(buf, input, end)
+			if err.expected != "" {
+				return lit, err
+			}
+			end += n
+
+		case c < ' ' && o.Strict && (len(closing) == 1 || (c != '\n' && c != '\r' && c != '\t')):
+			return lit, Error{pos: end, expected: "escaped control character"}
+
+		default:
+			// a lone quote inside triple quotes, a backslash in a raw string or an allowed control character
+			if buf != nil {
+				buf.WriteByte(c)
+			}
+			end++
+		}
+	}
+
+	return lit, Error{pos: litpos, expected: closing}
+}
+
+// unescape writes the escape sequence at input[pos] to buf, and returns how long it was
+func (o *StringOptions) unescape(buf *strings.Builder, input string, pos int) (int, Error) {
+	c := input[pos+1]
+	switch {
+	case c == 'u' && o.BracedUnicode && pos+2 < len(input) && input[pos+2] == '{':
+		digits := strings.IndexByte(input[pos+3:], '}')
+		if digits < 0 {
+			return 0, Error{pos: pos + 3, expected: "}"}
+		}
+		r, ok := unhex(input[pos+3 : pos+3+digits])
+		if !ok || digits < 1 || digits > 6 || !utf8.ValidRune(r) {
+			return 0, Error{pos: pos + 3, expected: "[a-f0-9]{1,6}"}
+		}
+		buf.WriteRune(r)
+		return digits + 4, Error{}
+
+	case c == 'u':
+		r, err := unhexAt(input, pos+2, 4)
+		if err.expected != "" {
+			return 0, err
+		}
+		if o.Surrogates && utf16.IsSurrogate(r) {
+			if pos+12 <= len(input) && input[pos+6] == '\\' && input[pos+7] == 'u' {
+				if low, err := unhexAt(input, pos+8, 4); err.expected == "" {
+					if joined := utf16.DecodeRune(r, low); joined != utf8.RuneError {
+						buf.WriteRune(joined)
+						return 12, Error{}
 					}
-				} else {
-					r, w := utf8.DecodeRuneInString(ps.Input[end:])
-					end += w
-					buf.WriteRune(r)
 				}
 			}
+			if o.Strict {
+				return 0, Error{pos: pos, expected: "surrogate pair"}
+			}
 		}
+		buf.WriteRune(r)
+		return 6, Error{}
 
-		ps.ErrorHere(string(quote))
-	})
+	case c == 'x' && o.Hex:
+		r, err := unhexAt(input, pos+2, 2)
+		if err.expected != "" {
+			return 0, err
+		}
+		buf.WriteRune(r)
+		return 4, Error{}
+	}
+
+	if o.Strict && !o.knownEscape(c) {
+		return 0, Error{pos: pos + 1, expected: "escape sequence"}
+	}
+	if o.ControlEscapes {
+		c = unescape(c)
+	}
+	buf.WriteByte(c)
+	return 2, Error{}
+}
+
+// knownEscapes are the escapes from C and json, apart from \u and \x which are handled separately
+const knownEscapes = "abfnrtv\\/\"'"
+
+// knownEscape reports whether Strict allows c after a backslash
+func (o *StringOptions) knownEscape(c byte) bool {
+	if o.Escapes != "" {
+		return stringContainsByte(o.Escapes, c)
+	}
+	return stringContainsByte(knownEscapes, c) || stringContainsByte(o.Quotes, c)
+}
+
+// unhexAt reads n hex digits at input[pos]
+func unhexAt(input string, pos int, n int) (rune, Error) {
+	if pos+n > len(input) {
+		return 0, Error{pos: pos, expected: "[a-f0-9]{" + strconv.Itoa(n) + "}"}
+	}
+	r, ok := unhex(input[pos : pos+n])
+	if !ok {
+		return 0, Error{pos: pos, expected: "[a-f0-9]"}
+	}
+	return r, Error{}
 }
 
 // NumberLit matches a floating point or integer number and returns it as a int64 or float64 in .Result
 func NumberLit() Parser {
-	return NewParser("number literal", func(ps *State, node *Result) {
+	return NumberLitWith(NumberOptions{})
+}
+
+// NumberResult is the type of .Result NumberLitWith returns
+type NumberResult int
+
+const (
+	// NumberNative returns an int64, or a float64 for numbers with a fraction or exponent, see OverflowFloats
+	NumberNative NumberResult = iota
+	// NumberBig returns a *big.Int, or a *big.Float with enough precision for every digit of the number
+	NumberBig
+	// NumberDecimal returns the number as a string of decimal digits, without underscores or a leading +, for
+	// decimal libraries that need the exact digits, eg for money
+	NumberDecimal
+)
+
+// NumberOptions are the dialect of number literal NumberLitWith matches. The zero value is NumberLit.
+type NumberOptions struct {
+	// Prefixes allows 0x hex, 0o octal and 0b binary integers
+	Prefixes bool
+	// Underscores allows _ between digits, eg 1_000_000
+	Underscores bool
+	// Unsigned doesnt allow a leading + or -, for grammars that parse signs as operators
+	Unsigned bool
+	// InfNaN allows inf, infinity and nan in any case, like strconv.ParseFloat. NumberBig cant return NaN, so
+	// it doesnt match it.
+	InfNaN bool
+	// Strict only matches the json grammar for numbers: no leading zeros or +, and digits on both sides of a .
+	// It matches as much of the input as it can, so 01 is the number 0 followed by 1.
+	Strict bool
+	// Result is the type of .Result
+	Result NumberResult
+	// OverflowFloats returns integers too big for an int64 as a float64, like encoding/json does. Without it they
+	// dont match. It only applies to NumberNative.
+	OverflowFloats bool
+}
+
+// NumberLitWith is NumberLit for other dialects of number literals
+func NumberLitWith(opts NumberOptions) Parser {
+	var starts string
+	if opts.InfNaN {
+		starts = "iInN"
+	}
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarNumberLit, Name: "number literal", starts: starts}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		startpos := ps.Pos
 		ps.WS(ps)
-		end := ps.Pos
-		float := false
-		inputLen := len(ps.Input)
 
-		if end < inputLen && (ps.Input[end] == '-' || ps.Input[end] == '+') {
-			end++
+		end, value, ok := opts.scan(ps.Input, ps.Pos)
+		if !ok {
+			ps.ErrorHere("number")
+			ps.Pos = startpos
+			return
 		}
 
-		for end < inputLen && ps.Input[end] >= '0' && ps.Input[end] <= '9' {
-			end++
+		node.Result = value
+		node.Start = ps.Pos
+		node.End = end
+		ps.Pos = end
+	})
+}
+
+// scan matches the number at pos and converts it
+func (o *NumberOptions) scan(input string, pos int) (end int, value interface{}, ok bool) {
+	end = pos
+	neg := false
+	if end < len(input) && !o.Unsigned && (input[end] == '-' || (input[end] == '+' && !o.Strict)) {
+		neg = input[end] == '-'
+		end++
+	}
+
+	if o.InfNaN {
+		if n, special := matchInfNaN(input[end:]); n > 0 {
+			value, ok = o.special(special, neg)
+			return end + n, value, ok
 		}
+	}
 
-		if end < inputLen && ps.Input[end] == '.' {
+	if o.Prefixes && end+1 < len(input) && input[end] == '0' {
+		base := 0
+		switch input[end+1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		// without any digits after it, 0x is just a 0
+		if digitsEnd := o.digits(input, end+2, base); base != 0 && digitsEnd > end+2 {
+			value, ok = o.integer(input[end+2:digitsEnd], neg, base)
+			return digitsEnd, value, ok
+		}
+	}
+
+	intStart := end
+	if o.Strict && end < len(input) && input[end] == '0' {
+		end++
+	} else {
+		end = o.digits(input, end, 10)
+	}
+	intEnd := end
+	if o.Strict && intEnd == intStart {
+		return pos, nil, false
+	}
+
+	float := false
+	if end < len(input) && input[end] == '.' {
+		if fracEnd := o.digits(input, end+1, 10); !o.Strict || fracEnd > end+1 {
 			float = true
-			end++
+			end = fracEnd
 		}
+	}
+	if end == intStart || (end == intStart+1 && input[intStart] == '.') {
+		return pos, nil, false
+	}
 
-		for end < inputLen && ps.Input[end] >= '0' && ps.Input[end] <= '9' {
-			end++
+	if end < len(input) && (input[end] == 'e' || input[end] == 'E') {
+		expStart := end + 1
+		if expStart < len(input) && (input[expStart] == '-' || input[expStart] == '+') {
+			expStart++
+		}
+		expEnd := o.digits(input, expStart, 10)
+		if expEnd == expStart && !o.Strict {
+			// a number cant end in an e
+			return pos, nil, false
+		}
+		if expEnd > expStart {
+			float = true
+			end = expEnd
 		}
+	}
+
+	text := input[pos:end]
+	if o.Underscores {
+		text = strings.ReplaceAll(text, "_", "")
+	}
+	if !float {
+		value, ok = o.integer(strings.TrimLeft(text, "+-"), neg, 10)
+		return end, value, ok
+	}
+	value, ok = o.float(text)
+	return end, value, ok
+}
 
-		if end < inputLen && (ps.Input[end] == 'e' || ps.Input[end] == 'E') {
+// digits returns the end of the digits in base at pos, or pos if there arent any
+func (o *NumberOptions) digits(input string, pos int, base int) int {
+	end := pos
+	for end < len(input) {
+		if isDigit(input[end], base) {
 			end++
-			float = true
+		} else if o.Underscores && input[end] == '_' && end > pos && end+1 < len(input) && isDigit(input[end+1], base) {
+			end++
+		} else {
+			break
+		}
+	}
+	return end
+}
 
-			if end < inputLen && (ps.Input[end] == '-' || ps.Input[end] == '+') {
-				end++
-			}
+func isDigit(c byte, base int) bool {
+	switch {
+	case base == 0:
+		return false
+	case base <= 10:
+		return c >= '0' && c < '0'+byte(base)
+	default:
+		return (c >= '0' && c <= '9') || (c >= 'a' && c < 'a'+byte(base-10)) || (c >= 'A' && c < 'A'+byte(base-10))
+	}
+}
 
-			for end < inputLen && ps.Input[end] >= '0' && ps.Input[end] <= '9' {
-				end++
+// integer converts digits, which may have underscores, in base
+func (o *NumberOptions) integer(digits string, neg bool, base int) (interface{}, bool) {
+	if o.Underscores {
+		digits = strings.ReplaceAll(digits, "_", "")
+	}
+	if neg {
+		digits = "-" + digits
+	}
+
+	switch o.Result {
+	case NumberBig, NumberDecimal:
+		i, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return nil, false
+		}
+		if o.Result == NumberDecimal {
+			if base == 10 {
+				return digits, true
 			}
+			return i.String(), true
 		}
+		return i, true
+	}
 
-		if end == ps.Pos {
-			ps.ErrorHere("number")
-			return
+	i, err := strconv.ParseInt(digits, base, 64)
+	if errors.Is(err, strconv.ErrRange) && o.OverflowFloats {
+		i, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return nil, false
 		}
+		f, _ := new(big.Float).SetInt(i).Float64()
+		return f, true
+	}
+	if err != nil {
+		return nil, false
+	}
+	return i, true
+}
 
-		var err error
-		if float {
-			node.Result, err = strconv.ParseFloat(ps.Input[ps.Pos:end], 10)
-		} else {
-			node.Result, err = strconv.ParseInt(ps.Input[ps.Pos:end], 10, 64)
+// float converts text, which has had its underscores removed
+func (o *NumberOptions) float(text string) (interface{}, bool) {
+	switch o.Result {
+	case NumberDecimal:
+		return strings.TrimPrefix(text, "+"), true
+	case NumberBig:
+		// every decimal digit needs a little under 4 bits
+		prec := uint(4 * len(text))
+		if prec < 64 {
+			prec = 64
 		}
+		f, _, err := big.ParseFloat(text, 10, prec, big.ToNearestEven)
 		if err != nil {
-			ps.ErrorHere("number")
-			return
+			return nil, false
 		}
-		node.Start = ps.Pos
-		node.End = end
-		ps.Pos = end
-	})
+		return f, true
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+// special converts inf or nan
+func (o *NumberOptions) special(special string, neg bool) (interface{}, bool) {
+	switch o.Result {
+	case NumberDecimal:
+		if neg && special == "Inf" {
+			return "-Inf", true
+		}
+		return special, true
+	case NumberBig:
+		if special == "NaN" {
+			return nil, false
+		}
+		return new(big.Float).SetInf(neg), true
+	}
+
+	if special == "NaN" {
+		return math.NaN(), true
+	}
+	if neg {
+		return math.Inf(-1), true
+	}
+	return math.Inf(1), true
+}
+
+// matchInfNaN returns the length of the inf, infinity or nan at the start of s, and whether it was Inf or NaN
+func matchInfNaN(s string) (int, string) {
+	for _, word := range []string{"infinity", "inf", "nan"} {
+		if len(s) >= len(word) && strings.EqualFold(s[:len(word)], word) && !wordRuneAt(s, len(word)) {
+			if word == "nan" {
+				return len(word), "NaN"
+			}
+			return len(word), "Inf"
+		}
+	}
+	return 0, ""
 }
 
 func stringContainsByte(s string, b byte) bool {
@@ -158,6 +538,29 @@ func stringContainsByte(s string, b byte) bool {
 	return false
 }
 
+// unescape returns the character a backslash escape stands for, eg n for a newline.
+// Anything that isnt a known escape stands for itself.
+func unescape(c byte) byte {
+	switch c {
+	case 'a':
+		return '\a'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'v':
+		return '\v'
+	default:
+		return c
+	}
+}
+
 func unhex(b string) (v rune, ok bool) {
 	for _, c := range b {
 		v <<= 4
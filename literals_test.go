@@ -1,6 +1,8 @@
 package goparsify
 
 import (
+	"math"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -56,6 +58,18 @@ func TestStringLit(t *testing.T) {
 		require.Equal(t, ``, p.Get())
 	})
 
+	t.Run("test escaped letters stand for themselves", func(t *testing.T) {
+		result, p := runParser(`"a\tb\nc\rd\be\f\qg"`, parser)
+		require.Equal(t, "atbncrdbefqg", result.Token)
+		require.Equal(t, ``, p.Get())
+	})
+
+	t.Run("test eof", func(t *testing.T) {
+		_, p := runParser(`  `, parser)
+		require.Equal(t, `"'`, p.Error.expected)
+		require.Equal(t, 0, p.Pos)
+	})
+
 	t.Run("test unicode chars", func(t *testing.T) {
 		result, p := runParser(`"hello 👺 my little goblin"`, parser)
 		require.Equal(t, `hello 👺 my little goblin`, result.Token)
@@ -82,6 +96,286 @@ func TestStringLit(t *testing.T) {
 	})
 }
 
+func TestStringLitWith(t *testing.T) {
+	parse := func(opts StringOptions, input string) (Result, *State) {
+		return runParser(input, StringLitWith(opts))
+	}
+
+	t.Run("c escapes", func(t *testing.T) {
+		result, _ := parse(StringOptions{Quotes: `"`, ControlEscapes: true}, `"\a\v\/\\"`)
+		require.Equal(t, "\a\v/\\", result.Token)
+
+		// they are just the escaped letter by default
+		result, _ = parse(StringOptions{Quotes: `"`}, `"\a\v\/\\"`)
+		require.Equal(t, "av/\\", result.Token)
+	})
+
+	t.Run("hex", func(t *testing.T) {
+		result, p := parse(StringOptions{Quotes: `"`, Hex: true}, `"\x41\xe9"`)
+		require.Equal(t, "Aé", result.Token)
+		require.Equal(t, "", p.Get())
+
+		_, p = parse(StringOptions{Quotes: `"`, Hex: true}, `"\xg1"`)
+		require.Equal(t, "offset 3: expected [a-f0-9]", p.Error.Error())
+
+		_, p = parse(StringOptions{Quotes: `"`, Hex: true}, `"\x`)
+		require.Equal(t, "offset 3: expected [a-f0-9]{2}", p.Error.Error())
+
+		result, _ = parse(StringOptions{Quotes: `"`}, `"\x41"`)
+		require.Equal(t, "x41", result.Token)
+	})
+
+	t.Run("braced unicode", func(t *testing.T) {
+		opts := StringOptions{Quotes: `"`, BracedUnicode: true}
+		result, p := parse(opts, `"\u{1F47A} \u{e9}\u00e9"`)
+		require.Equal(t, "👺 éé", result.Token)
+		require.Equal(t, "", p.Get())
+
+		_, p = parse(opts, `"\u{}"`)
+		require.Equal(t, "offset 4: expected [a-f0-9]{1,6}", p.Error.Error())
+		_, p = parse(opts, `"\u{110000}"`)
+		require.Equal(t, "offset 4: expected [a-f0-9]{1,6}", p.Error.Error())
+		_, p = parse(opts, `"\u{41"`)
+		require.Equal(t, "offset 4: expected }", p.Error.Error())
+	})
+
+	t.Run("surrogates", func(t *testing.T) {
+		opts := StringOptions{Quotes: `"`, Surrogates: true}
+		result, _ := parse(opts, `"\ud83d\ude3a!"`)
+		require.Equal(t, "😺!", result.Token)
+
+		result, _ = parse(opts, `"\ud83d!"`)
+		require.Equal(t, "\uFFFD!", result.Token)
+
+		result, _ = parse(opts, `"\ud83d\u0041"`)
+		require.Equal(t, "\uFFFDA", result.Token)
+
+		opts.Strict = true
+		_, p := parse(opts, `"a\ude3a"`)
+		require.Equal(t, "offset 2: expected surrogate pair", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		result, p := parse(StringOptions{Quotes: "`", Raw: true}, "`C:\\path\\n`")
+		require.Equal(t, `C:\path\n`, result.Token)
+		require.Equal(t, "", p.Get())
+	})
+
+	t.Run("raw prefixes", func(t *testing.T) {
+		opts := StringOptions{Quotes: `"'`, RawPrefixes: "rR", ControlEscapes: true}
+		result, p := parse(opts, ` r"\d+" x`)
+		require.Equal(t, `\d+`, result.Token)
+		require.Equal(t, 3, result.Start)
+		require.Equal(t, " x", p.Get())
+
+		result, _ = parse(opts, `"\t"`)
+		require.Equal(t, "\t", result.Token)
+
+		_, p = parse(opts, `rx`)
+		require.Equal(t, `offset 0: expected "'`, p.Error.Error())
+
+		// Any knows raw strings can start with a prefix
+		result, _ = runParser(`r"\d"`, Any(Chars("0-9"), StringLitWith(opts)))
+		require.Equal(t, `\d`, result.Token)
+	})
+
+	t.Run("doubled quotes", func(t *testing.T) {
+		opts := StringOptions{Quotes: `'`, DoubledQuotes: true, Raw: true}
+		result, p := parse(opts, `'it''s' x`)
+		require.Equal(t, "it's", result.Token)
+		require.Equal(t, " x", p.Get())
+
+		result, _ = parse(opts, `'''' x`)
+		require.Equal(t, "'", result.Token)
+
+		result, p = parse(opts, `'' x`)
+		require.Equal(t, "", result.Token)
+		require.Equal(t, " x", p.Get())
+	})
+
+	t.Run("triple quotes", func(t *testing.T) {
+		opts := StringOptions{Quotes: `"`, TripleQuotes: true, Strict: true, ControlEscapes: true}
+		result, p := parse(opts, "\"\"\"one \"two\"\n\tthree\\n\"\"\" x")
+		require.Equal(t, "one \"two\"\n\tthree\n", result.Token)
+		require.Equal(t, 3, result.Start)
+		require.Equal(t, " x", p.Get())
+
+		result, p = parse(opts, `"" x`)
+		require.Equal(t, "", result.Token)
+		require.Equal(t, " x", p.Get())
+
+		_, p = parse(opts, `"""abc""`)
+		require.Equal(t, `offset 0: expected """`, p.Error.Error())
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		opts := StringOptions{Quotes: `"`, Strict: true, ControlEscapes: true}
+		result, _ := parse(opts, `"\"\\\/\b\f\n\r\t"`)
+		require.Equal(t, "\"\\/\b\f\n\r\t", result.Token)
+
+		_, p := parse(opts, `"\q"`)
+		require.Equal(t, "offset 2: expected escape sequence", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+
+		_, p = parse(opts, "\"a\nb\"")
+		require.Equal(t, "offset 2: expected escaped control character", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+
+		result, _ = parse(StringOptions{Quotes: `"`}, "\"a\nb\"")
+		require.Equal(t, "a\nb", result.Token)
+
+		// C escapes that json doesnt have
+		result, _ = parse(opts, `"\a\v\'"`)
+		require.Equal(t, "\a\v'", result.Token)
+	})
+
+	t.Run("strict json", func(t *testing.T) {
+		opts := StringOptions{Quotes: `"`, Strict: true, ControlEscapes: true, Escapes: JSONEscapes}
+		result, _ := parse(opts, `"\"\\\/\b\f\n\r\t\u00e9"`)
+		require.Equal(t, "\"\\/\b\f\n\r\té", result.Token)
+
+		for _, escape := range []string{`\a`, `\v`, `\'`} {
+			_, p := parse(opts, `"x`+escape+`"`)
+			require.Equal(t, "offset 3: expected escape sequence", p.Error.Error(), escape)
+		}
+	})
+
+	t.Run("keeps invalid utf8 after escapes", func(t *testing.T) {
+		result, _ := parse(StringOptions{Quotes: `"`, ControlEscapes: true}, "\"\\n\xff\"")
+		require.Equal(t, "\n\xff", result.Token)
+	})
+}
+
+func TestNumberLitWith(t *testing.T) {
+	parse := func(opts NumberOptions, input string) (Result, *State) {
+		return runParser(input, NumberLitWith(opts))
+	}
+
+	t.Run("prefixes", func(t *testing.T) {
+		opts := NumberOptions{Prefixes: true}
+		for input, expected := range map[string]int64{"0x1F": 31, "-0XfF": -255, "0o17": 15, "0b101": 5, "017": 17} {
+			result, p := parse(opts, input)
+			require.Equal(t, expected, result.Result, input)
+			require.Equal(t, "", p.Get(), input)
+		}
+
+		result, p := parse(opts, "0xg")
+		require.Equal(t, int64(0), result.Result)
+		require.Equal(t, "xg", p.Get())
+
+		result, _ = parse(opts, "0x1_0")
+		require.Equal(t, int64(1), result.Result)
+
+		_, p = parse(opts, "0xffffffffffffffff")
+		require.Equal(t, "offset 0: expected number", p.Error.Error())
+	})
+
+	t.Run("overflow floats", func(t *testing.T) {
+		opts := NumberOptions{Prefixes: true, OverflowFloats: true}
+		result, _ := parse(opts, "0xffffffffffffffff")
+		require.Equal(t, float64(1<<64-1), result.Result)
+
+		result, _ = parse(opts, "-10000000000000000000")
+		require.Equal(t, -1e19, result.Result)
+
+		result, _ = parse(opts, "9223372036854775807")
+		require.Equal(t, int64(math.MaxInt64), result.Result)
+	})
+
+	t.Run("underscores", func(t *testing.T) {
+		opts := NumberOptions{Underscores: true, Prefixes: true}
+		result, p := parse(opts, "1_000_000.000_1e1_0")
+		require.Equal(t, 1000000.0001e10, result.Result)
+		require.Equal(t, "", p.Get())
+
+		result, _ = parse(opts, "0b1_1")
+		require.Equal(t, int64(3), result.Result)
+
+		for input, rest := range map[string]string{"1__0": "__0", "1_": "_", "1_.5": "_.5"} {
+			result, p = parse(opts, input)
+			require.Equal(t, int64(1), result.Result, input)
+			require.Equal(t, rest, p.Get(), input)
+		}
+
+		_, p = parse(opts, "_1")
+		require.Equal(t, "offset 0: expected number", p.Error.Error())
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		_, p := parse(NumberOptions{Unsigned: true}, "-1")
+		require.Equal(t, "offset 0: expected number", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+	})
+
+	t.Run("inf and nan", func(t *testing.T) {
+		opts := NumberOptions{InfNaN: true}
+		result, p := parse(opts, "-Infinity,")
+		require.Equal(t, math.Inf(-1), result.Result)
+		require.Equal(t, ",", p.Get())
+
+		result, _ = parse(opts, "inf")
+		require.Equal(t, math.Inf(1), result.Result)
+
+		result, _ = parse(opts, "NaN")
+		require.True(t, math.IsNaN(result.Result.(float64)))
+
+		_, p = parse(opts, "info")
+		require.Equal(t, "offset 0: expected number", p.Error.Error())
+
+		result, _ = parse(NumberOptions{InfNaN: true, Result: NumberDecimal}, "-inf")
+		require.Equal(t, "-Inf", result.Result)
+
+		_, p = parse(NumberOptions{InfNaN: true, Result: NumberBig}, "nan")
+		require.True(t, p.Errored())
+
+		// Any knows numbers can start with an i or n now
+		result, _ = runParser("nan", Any("x", NumberLitWith(opts)))
+		require.True(t, math.IsNaN(result.Result.(float64)))
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		opts := NumberOptions{Strict: true}
+		for input, rest := range map[string]string{"0": "", "-0.5e+3": "", "01": "1", "1.": ".", "1.e3": ".e3", "1e": "e", "2E-": "E-"} {
+			_, p := parse(opts, input)
+			require.False(t, p.Errored(), input)
+			require.Equal(t, rest, p.Get(), input)
+		}
+		for _, input := range []string{"+1", ".5", "-", "-.5"} {
+			_, p := parse(opts, input)
+			require.Equal(t, "offset 0: expected number", p.Error.Error(), input)
+		}
+	})
+
+	t.Run("big", func(t *testing.T) {
+		opts := NumberOptions{Result: NumberBig, Prefixes: true}
+		result, _ := parse(opts, "-123456789012345678901234567890")
+		expected, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+		require.Equal(t, expected, result.Result)
+
+		result, _ = parse(opts, "0xffffffffffffffffffff")
+		require.Equal(t, "1208925819614629174706175", result.Result.(*big.Int).String())
+
+		result, _ = parse(opts, "0.1000000000000000000000000001")
+		require.Equal(t, "0.1000000000000000000000000001", result.Result.(*big.Float).Text('f', 28))
+	})
+
+	t.Run("decimal", func(t *testing.T) {
+		opts := NumberOptions{Result: NumberDecimal, Prefixes: true, Underscores: true}
+		for input, expected := range map[string]string{
+			"+1_234.50":                    "1234.50",
+			"-0.10e-2":                     "-0.10e-2",
+			"0x10":                         "16",
+			"007":                          "007",
+			"123456789012345678901234.001": "123456789012345678901234.001",
+		} {
+			result, p := parse(opts, input)
+			require.Equal(t, expected, result.Result, input)
+			require.Equal(t, "", p.Get(), input)
+		}
+	})
+}
+
 func TestUnhex(t *testing.T) {
 	tests := map[int64]string{
 		0xF:        "F",
@@ -162,6 +456,12 @@ func TestNumberLit(t *testing.T) {
 		require.Equal(t, "foo", p.Get())
 	})
 
+	t.Run("int overflow", func(t *testing.T) {
+		_, p := runParser("10000000000000000000", parser)
+		require.Equal(t, "offset 0: expected number", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+	})
+
 	t.Run("non matching string", func(t *testing.T) {
 		_, p := runParser("foo", parser)
 		require.Equal(t, "offset 0: expected number", p.Error.Error())
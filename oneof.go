@@ -0,0 +1,175 @@
+package goparsify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// OneOfOptions change how OneOfWith and OneOfMapWith match
+type OneOfOptions struct {
+	// FoldCase matches literals regardless of case, using unicode simple case folding
+	FoldCase bool
+	// WordBoundary only matches a literal ending in a letter, digit or _ when it isnt followed by another one,
+	// so the keyword if doesnt match the start of iffy
+	WordBoundary bool
+}
+
+// OneOf matches the longest of the given literals and returns it in .Token. Unlike Any(literals...) the order
+// doesnt matter, and a single pass is made over the input however many literals there are, so it suits large sets
+// of keywords or operators.
+func OneOf(literals ...string) Parser {
+	return OneOfWith(OneOfOptions{}, literals...)
+}
+
+// OneOfWith is OneOf with options. .Token is the text that was matched, which may differ in case from the literal
+// with FoldCase, and .Result is the literal as it was given.
+func OneOfWith(opts OneOfOptions, literals ...string) Parser {
+	values := make([]interface{}, len(literals))
+	for i, literal := range literals {
+		values[i] = literal
+	}
+	return oneOfImpl(opts, literals, values)
+}
+
+// OneOfMap matches the longest of the keys of values, returning the text matched in .Token and its value in .Result
+// like Bind
+func OneOfMap(values map[string]interface{}) Parser {
+	return OneOfMapWith(OneOfOptions{}, values)
+}
+
+// OneOfMapWith is OneOfMap with options
+func OneOfMapWith(opts OneOfOptions, values map[string]interface{}) Parser {
+	literals := make([]string, 0, len(values))
+	for literal := range values {
+		literals = append(literals, literal)
+	}
+	// keys that fold to the same thing are given in a stable order, the last one wins
+	sort.Strings(literals)
+
+	ordered := make([]interface{}, len(literals))
+	for i, literal := range literals {
+		ordered[i] = values[literal]
+	}
+	return oneOfImpl(opts, literals, ordered)
+}
+
+type trieNode struct {
+	edges    map[rune]*trieNode
+	terminal bool
+	// value is what matching up to here returns in .Result, when terminal
+	value interface{}
+	// wordEnd is true when literal ends in a word character, see OneOfOptions.WordBoundary
+	wordEnd bool
+}
+
+func oneOfImpl(opts OneOfOptions, literals []string, values []interface{}) Parser {
+	root := &trieNode{}
+	for i, literal := range literals {
+		node := root
+		for _, r := range literal {
+			if opts.FoldCase {
+				r = foldRune(r)
+			}
+			next := node.edges[r]
+			if next == nil {
+				if node.edges == nil {
+					node.edges = map[rune]*trieNode{}
+				}
+				next = &trieNode{}
+				node.edges[r] = next
+			}
+			node = next
+		}
+		last, _ := utf8.DecodeLastRuneInString(literal)
+		node.terminal = true
+		node.value = values[i]
+		node.wordEnd = literal != "" && isWordRune(last)
+	}
+
+	expected := describeLiterals(literals)
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarOneOf, Name: "OneOf()", Literals: literals, FoldCase: opts.FoldCase}
+	})
+
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		startpos := ps.Pos
+		ps.WS(ps)
+
+		var best *trieNode
+		bestEnd := 0
+		current := root
+		pos := ps.Pos
+		for {
+			if current.terminal && (!opts.WordBoundary || !current.wordEnd || !wordRuneAt(ps.Input, pos)) {
+				best = current
+				bestEnd = pos
+			}
+			if pos >= len(ps.Input) || current.edges == nil {
+				break
+			}
+
+			r, w := rune(ps.Input[pos]), 1
+			if r >= utf8.RuneSelf {
+				r, w = utf8.DecodeRuneInString(ps.Input[pos:])
+			}
+			if opts.FoldCase {
+				r = foldRune(r)
+			}
+			current = current.edges[r]
+			if current == nil {
+				break
+			}
+			pos += w
+		}
+
+		if best == nil {
+			ps.ErrorHere(expected)
+			ps.Pos = startpos
+			return
+		}
+
+		node.Token = ps.Input[ps.Pos:bestEnd]
+		node.Result = best.value
+		node.Start = ps.Pos
+		node.End = bestEnd
+		ps.Pos = bestEnd
+	})
+}
+
+// describeLiterals is the error for a failed OneOf, which can have hundreds of literals
+func describeLiterals(literals []string) string {
+	const max = 10
+	if len(literals) <= max {
+		return strings.Join(literals, " or ")
+	}
+	return fmt.Sprintf("%s or %d more", strings.Join(literals[:max], " or "), len(literals)-max)
+}
+
+// foldRune maps every rune that is the same regardless of case to the same rune, the smallest of them
+func foldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func wordRuneAt(s string, pos int) bool {
+	if pos >= len(s) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s[pos:])
+	return isWordRune(r)
+}
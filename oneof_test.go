@@ -0,0 +1,133 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOneOf(t *testing.T) {
+	parser := OneOf("<", "<=", "<<", "<<=", "if", "in")
+
+	t.Run("matches longest", func(t *testing.T) {
+		for input, expected := range map[string]string{"<": "<", "<= 1": "<=", "<<": "<<", "<<=x": "<<=", "<<<": "<<"} {
+			node, ps := runParser(input, parser)
+			require.False(t, ps.Errored(), input)
+			require.Equal(t, expected, node.Token, input)
+			require.Equal(t, len(expected), ps.Pos, input)
+		}
+	})
+
+	t.Run("backs up to the last literal", func(t *testing.T) {
+		node, ps := runParser("i", OneOf("in", "int", "i"))
+		require.Equal(t, "i", node.Token)
+		require.Equal(t, 1, ps.Pos)
+
+		node, ps = runParser("inx", OneOf("in", "intx"))
+		require.Equal(t, "in", node.Token)
+		require.Equal(t, 2, ps.Pos)
+	})
+
+	t.Run("skips whitespace", func(t *testing.T) {
+		node, ps := runParser("  if", parser)
+		require.Equal(t, "if", node.Token)
+		require.Equal(t, 2, node.Start)
+		require.Equal(t, 4, node.End)
+		require.Equal(t, 4, ps.Pos)
+	})
+
+	t.Run("returns errors", func(t *testing.T) {
+		_, ps := runParser(" x", parser)
+		require.Equal(t, "offset 1: expected < or <= or << or <<= or if or in", ps.Error.Error())
+		require.Equal(t, 0, ps.Pos)
+
+		_, ps = runParser("", parser)
+		require.True(t, ps.Errored())
+	})
+
+	t.Run("shortens long errors", func(t *testing.T) {
+		_, ps := runParser("x", OneOf("a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"))
+		require.Equal(t, "a or b or c or d or e or f or g or h or i or j or 2 more", ps.Error.expected)
+	})
+
+	t.Run("unicode", func(t *testing.T) {
+		node, _ := runParser("λx", OneOf("λ", "λy", "→"))
+		require.Equal(t, "λ", node.Token)
+	})
+
+	t.Run("empty literal always matches", func(t *testing.T) {
+		node, ps := runParser("x", OneOf("", "y"))
+		require.False(t, ps.Errored())
+		require.Equal(t, "", node.Token)
+	})
+}
+
+func TestOneOfWith(t *testing.T) {
+	t.Run("fold case", func(t *testing.T) {
+		parser := OneOfWith(OneOfOptions{FoldCase: true}, "select", "ΣΑΣ", "k")
+		for input, expected := range map[string]string{"SELECT": "select", "sElEcT": "select", "σας": "ΣΑΣ", "K": "k"} {
+			node, ps := runParser(input, parser)
+			require.False(t, ps.Errored(), input)
+			require.Equal(t, input, node.Token, input)
+			require.Equal(t, expected, node.Result, input)
+			require.Equal(t, input, ps.Input[node.Start:node.End])
+		}
+
+		// the same as ExactFold
+		node, _ := runParser("SeLeCt", parser)
+		exact, _ := runParser("SeLeCt", ExactFold("select"))
+		require.Equal(t, exact.Token, node.Token)
+	})
+
+	t.Run("word boundary", func(t *testing.T) {
+		parser := OneOfWith(OneOfOptions{WordBoundary: true}, "if", "iffy", "+", "++", "i")
+
+		node, ps := runParser("if x", parser)
+		require.Equal(t, "if", node.Token)
+
+		_, ps = runParser("iff", parser)
+		require.True(t, ps.Errored())
+
+		node, _ = runParser("i f", parser)
+		require.Equal(t, "i", node.Token)
+
+		_, ps = runParser("ifé", OneOfWith(OneOfOptions{WordBoundary: true}, "if"))
+		require.True(t, ps.Errored())
+
+		node, _ = runParser("+x", parser)
+		require.Equal(t, "+", node.Token)
+
+		node, _ = runParser("if+", parser)
+		require.Equal(t, "if", node.Token)
+	})
+}
+
+func TestOneOfMap(t *testing.T) {
+	parser := OneOfMap(map[string]interface{}{"true": true, "false": false, "t": 1})
+
+	node, ps := runParser("true", parser)
+	require.False(t, ps.Errored())
+	require.Equal(t, true, node.Result)
+	require.Equal(t, "true", node.Token)
+
+	node, _ = runParser("tru", parser)
+	require.Equal(t, 1, node.Result)
+
+	_, ps = runParser("x", parser)
+	require.Equal(t, "false or t or true", ps.Error.expected)
+
+	node, _ = runParser("FALSE", OneOfMapWith(OneOfOptions{FoldCase: true}, map[string]interface{}{"false": false}))
+	require.Equal(t, false, node.Result)
+}
+
+func TestOneOfDispatch(t *testing.T) {
+	parser := Any(OneOfWith(OneOfOptions{FoldCase: true}, "kind", "<="), Chars("0-9"), OneOf("é"))
+	for _, input := range []string{"KIND", "Kind", "<=", "12", "é", "x", "<"} {
+		requireSameAsUndispatched(t, parser, input)
+	}
+
+	table := newDispatchTable([]*Grammar{Describe(OneOfWith(OneOfOptions{FoldCase: true}, "kind"))}, false)
+	require.NotZero(t, table.viable['K'])
+	require.NotZero(t, table.viable[0xe2]) // the kelvin sign
+	require.Zero(t, table.viable['x'])
+}
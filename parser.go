@@ -3,6 +3,7 @@ package goparsify
 import (
 	"fmt"
 	"regexp"
+	"regexp/syntax"
 	"strings"
 	"unicode/utf8"
 )
@@ -56,9 +57,15 @@ func Parsify(p Parserish) Parser {
 		return p
 	case *Parser:
 		// TODO: Maybe capture this stack and on nil show it? Is there a good error library to do this?
-		return func(ptr *State, node *Result) {
+		return refParser(p, func(ptr *State, node *Result) {
+			if ptr.maxDepth == 0 {
+				(*p)(ptr, node)
+				return
+			}
+			ptr.enter()
 			(*p)(ptr, node)
-		}
+			ptr.depth--
+		})
 	case string:
 		return Exact(p)
 	case func(*State):
@@ -82,8 +89,25 @@ func ParsifyAll(parsers ...Parserish) []Parser {
 // Run applies some input to a parser and returns the result, failing if the input isnt fully consumed.
 // It is a convenience method for the most common way to invoke a parser.
 func Run(parser Parserish, input string, ws ...VoidParser) (result interface{}, err error) {
+	ret, err := Parse(parser, input, ws...)
+	return ret.Result, err
+}
+
+// RunWithUser is Run, starting the parse with user as State.User
+func RunWithUser(parser Parserish, input string, user interface{}, ws ...VoidParser) (result interface{}, err error) {
+	ret, err := parse(parser, input, user, ws...)
+	return ret.Result, err
+}
+
+// Parse is Run, but returns the whole Result tree instead of just its .Result
+func Parse(parser Parserish, input string, ws ...VoidParser) (*Result, error) {
+	return parse(parser, input, nil, ws...)
+}
+
+func parse(parser Parserish, input string, user interface{}, ws ...VoidParser) (*Result, error) {
 	p := Parsify(parser)
 	ps := NewState(input)
+	ps.User = user
 	if len(ws) > 0 {
 		ps.WS = ws[0]
 	}
@@ -92,49 +116,315 @@ func Run(parser Parserish, input string, ws ...VoidParser) (result interface{},
 	p(ps, ret)
 	ps.WS(ps)
 
-	if ps.Error.expected != "" {
-		return ret.Result, &ps.Error
+	if ps.Errored() || ps.Get() != "" {
+		return ret, ps.parseError()
+	}
+
+	return ret, nil
+}
+
+// ParsePrefix parses the start of input from offset onwards, leaving whatever comes after the match for other code.
+// end is where the match ended, not counting any whitespace after it. On error end is offset. An offset outside of
+// input is an error.
+func ParsePrefix(parser Parserish, input string, offset int, ws ...VoidParser) (result *Result, end int, err error) {
+	if offset < 0 || offset > len(input) {
+		return nil, offset, fmt.Errorf("offset %d is outside of the %d byte input", offset, len(input))
+	}
+	p := Parsify(parser)
+	ps := NewState(input)
+	ps.Pos = offset
+	if len(ws) > 0 {
+		ps.WS = ws[0]
+	}
+
+	ret := NewResult(input)
+	p(ps, ret)
+	if ps.Errored() {
+		return ret, offset, ps.parseError()
 	}
+	return ret, ps.Pos, nil
+}
+
+// Scan finds the matches of parser in input, trying at every position in turn like regexp.FindAll does, and
+// continuing after the end of each match. Whitespace before a match isnt part of it. If n >= 0 it stops after
+// n matches.
+func Scan(parser Parserish, input string, n int, ws ...VoidParser) []*Result {
+	p := Parsify(parser)
+	ps := NewState(input)
+	if len(ws) > 0 {
+		ps.WS = ws[0]
+	}
+
+	// positions the parser is sure to fail at can be skipped
+	first, predicted := firstBytesOf(Describe(p), false, map[*Grammar]bool{})
+
+	var matches []*Result
+	for pos := 0; pos <= len(input) && (n < 0 || len(matches) < n); {
+		// whitespace is skipped first, so it isnt part of the match
+		ps.Pos = pos
+		ps.WS(ps)
+		pos = ps.Pos
+		if predicted && (pos == len(input) || !first.bytes.has(input[pos])) {
+			pos += runeWidth(input, pos)
+			continue
+		}
+
+		ps.Cut, ps.Error, ps.captures = 0, Error{}, nil
+		mark := ps.mark()
+		ret := NewResult(input)
+		p(ps, ret)
+		if ps.Errored() {
+			ps.reset(mark)
+			pos += runeWidth(input, pos)
+			continue
+		}
 
-	if ps.Get() != "" {
-		return ret.Result, UnparsedInputError{ps.Get()}
+		matches = append(matches, ret)
+		if ps.Pos > pos {
+			pos = ps.Pos
+		} else {
+			pos += runeWidth(input, pos)
+		}
 	}
+	return matches
+}
 
-	return ret.Result, nil
+// runeWidth is how many bytes the rune at pos takes up, or 1 at the end of input
+func runeWidth(input string, pos int) int {
+	if pos >= len(input) {
+		return 1
+	}
+	_, w := utf8.DecodeRuneInString(input[pos:])
+	return w
 }
 
 // Cut prevents backtracking beyond this point. Usually used after keywords when you
 // are sure this is the correct path. Improves performance and error reporting.
+//
+// Like a cut in Prolog or PEG, it commits the nearest Any, Maybe or repetition like ZeroOrMore around it to the
+// path it is on. If that path fails the Any fails without trying the alternatives after it, Maybe fails instead
+// of matching nothing, and the repetition fails instead of stopping before the item. Once they are done
+// the cut is forgotten, so it never stops a choice further out from backtracking.
 func Cut() Parser {
 	return func(ps *State, node *Result) {
 		ps.Cut = ps.Pos
 	}
 }
 
-// Regex returns a match if the regex successfully matches
+// cutAfter returns true if there was a cut after pos, so a choice that started at pos mustnt backtrack. The
+// furthest error a cut commits to is remembered, see parseError.
+func (s *State) cutAfter(pos int) bool {
+	if s.Cut <= pos {
+		return false
+	}
+	if s.Error.pos >= s.cutError.pos {
+		s.cutError = s.Error
+	}
+	return true
+}
+
+// parseError is the error for a parse that failed or stopped before the end of the input. A choice that failed
+// after a cut further on is most likely the real problem, rather than whatever gave up on it, so its error wins.
+func (s *State) parseError() error {
+	committed := s.cutError.expected != ""
+	var e Error
+	switch {
+	case s.Errored() && committed && s.cutError.pos > s.Error.pos, !s.Errored() && committed && s.cutError.pos >= s.Pos:
+		e = s.cutError
+	case s.Errored():
+		e = s.Error
+	default:
+		return UnparsedInputError{s.Get()}
+	}
+	// failing right where a separator was left after the last item, eg the comma of [1,2,], is down to the separator
+	if s.trailingSep > 0 && e.pos == s.trailingSep-1 {
+		e.unexpected = "trailing separator"
+	}
+	return &e
+}
+
+// Regex returns a match if the regex matches at the current position. The pattern is anchored there as a whole,
+// so alternations like a|b work as expected. Empty matches are errors.
+//
+// Capture groups are returned in .Child, with named groups getting their name as the .Label, so they can be found
+// with First. Groups that didnt take part in the match have an empty .Token and a .Start and .End of -1. If the
+// pattern has named groups, .Result is a map[string]string of the named groups that matched.
 func Regex(pattern string) Parser {
-	re := regexp.MustCompile("^" + pattern)
-	return NewParser(pattern, func(ps *State, node *Result) {
+	return regexImpl(pattern, regexp.MustCompile("^(?:"+pattern+")"), true)
+}
+
+// RegexMatcher is Regex for an expression that has already been compiled, eg to keep re.Longest(). It is used as
+// it is, and only matches at the current position. Start the pattern with ^ so it isnt searched for further along
+// the input first.
+func RegexMatcher(re *regexp.Regexp) Parser {
+	return regexImpl(re.String(), re, false)
+}
+
+// regexImpl matches re at the current position. Unless it is anchored it may find a match further along, which
+// isnt one.
+func regexImpl(pattern string, re *regexp.Regexp, anchored bool) Parser {
+	window := regexWindow(pattern)
+	groups := re.SubexpNames()[1:]
+	named := false
+	for _, name := range groups {
+		named = named || name != ""
+	}
+
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarRegex, Name: pattern, Literal: pattern}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		startpos := ps.Pos
 		ps.WS(ps)
-		if match := re.FindString(ps.Get()); match != "" {
-			node.Start = ps.Pos
-			node.End = ps.Pos + len(match)
-			ps.Advance(len(match))
-			node.Token = match
+
+		// regexp is a lot slower on long inputs, so only give it as much as the pattern could match
+		input := ps.Get()
+		if window > 0 && len(input) > window {
+			input = input[:window]
+		}
+
+		if len(groups) == 0 {
+			match := ""
+			if anchored {
+				match = re.FindString(input)
+			} else if loc := re.FindStringIndex(input); loc != nil && loc[0] == 0 {
+				match = input[:loc[1]]
+			}
+			if match != "" {
+				node.Start = ps.Pos
+				node.End = ps.Pos + len(match)
+				ps.Advance(len(match))
+				node.Token = match
+				return
+			}
+			ps.ErrorHere(pattern)
+			ps.Pos = startpos
+			return
+		}
+
+		loc := re.FindStringSubmatchIndex(input)
+		if loc == nil || loc[0] != 0 || loc[1] == 0 {
+			ps.ErrorHere(pattern)
+			ps.Pos = startpos
 			return
 		}
-		ps.ErrorHere(pattern)
+
+		var values map[string]string
+		if named {
+			values = map[string]string{}
+		}
+		node.Child = ps.arena.alloc(len(groups), ps.Input)
+		for i, name := range groups {
+			child := &node.Child[i]
+			child.Label = name
+			child.Start, child.End = -1, -1
+			if start, end := loc[2*i+2], loc[2*i+3]; start >= 0 {
+				child.Start = ps.Pos + start
+				child.End = ps.Pos + end
+				child.Token = ps.Input[child.Start:child.End]
+				if name != "" {
+					values[name] = child.Token
+				}
+			}
+		}
+		if named {
+			node.Result = values
+		}
+
+		node.Start = ps.Pos
+		node.End = ps.Pos + loc[1]
+		node.Token = ps.Input[node.Start:node.End]
+		ps.Advance(loc[1])
 	})
 }
 
+// regexWindow is how much input a pattern needs to see to match the same as it would on all of it, or 0 if it can
+// match any length. Thats the longest match, and one more rune for \b or $ to look at.
+func regexWindow(pattern string) int {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0
+	}
+	runes := maxRegexLength(re)
+	if runes < 0 || runes > 1024 {
+		return 0
+	}
+	return (runes + 1) * utf8.UTFMax
+}
+
+// maxRegexLength is the most runes re can match, or -1 if there is no limit
+func maxRegexLength(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1
+	case syntax.OpCapture, syntax.OpQuest:
+		return maxRegexLength(re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus:
+		return -1
+	case syntax.OpRepeat:
+		sub := maxRegexLength(re.Sub[0])
+		if re.Max < 0 || sub < 0 || re.Max > 1024 || sub > 1024 {
+			return -1
+		}
+		return re.Max * sub
+	case syntax.OpConcat, syntax.OpAlternate:
+		total := 0
+		for _, sub := range re.Sub {
+			n := maxRegexLength(sub)
+			if n < 0 {
+				return -1
+			}
+			if re.Op == syntax.OpConcat {
+				total += n
+			} else if n > total {
+				total = n
+			}
+			if total > 1024 {
+				return -1
+			}
+		}
+		return total
+	}
+	// empty matches, anchors and word boundaries dont consume anything
+	return 0
+}
+
 // Exact will fully match the exact string supplied, or error. The match will be stored in .Token
 func Exact(match string) Parser {
+	return exactImpl(match, false)
+}
+
+// ExactFold is Exact, but matches regardless of case using unicode simple case folding, so ExactFold("select")
+// matches SELECT and Select. .Token is the input as written.
+func ExactFold(match string) Parser {
+	return exactImpl(match, true)
+}
+
+func exactImpl(match string, fold bool) Parser {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarExact, Name: match, Literal: match, FoldCase: fold}
+	})
+
 	if len(match) == 1 {
 		matchByte := match[0]
-		return NewParser(match, func(ps *State, node *Result) {
+		return newParser(g, func(ps *State, node *Result) {
+			if ps.Tracer != nil {
+				defer g.enter(ps, node).leave()
+			}
+			startpos := ps.Pos
 			ps.WS(ps)
+			if fold || ps.FoldCase {
+				exactFold(ps, node, match, startpos)
+				return
+			}
 			if ps.Pos >= len(ps.Input) || ps.Input[ps.Pos] != matchByte {
 				ps.ErrorHere(match)
+				ps.Pos = startpos
 				return
 			}
 
@@ -146,10 +436,19 @@ func Exact(match string) Parser {
 		})
 	}
 
-	return NewParser(match, func(ps *State, node *Result) {
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		startpos := ps.Pos
 		ps.WS(ps)
+		if fold || ps.FoldCase {
+			exactFold(ps, node, match, startpos)
+			return
+		}
 		if !strings.HasPrefix(ps.Get(), match) {
 			ps.ErrorHere(match)
+			ps.Pos = startpos
 			return
 		}
 
@@ -162,6 +461,55 @@ func Exact(match string) Parser {
 	})
 }
 
+func exactFold(ps *State, node *Result, match string, startpos int) {
+	n := foldPrefix(ps.Get(), match)
+	if n < 0 {
+		ps.ErrorHere(match)
+		ps.Pos = startpos
+		return
+	}
+
+	node.Start = ps.Pos
+	node.End = ps.Pos + n
+	node.Token = ps.Input[ps.Pos : ps.Pos+n]
+	ps.Advance(n)
+}
+
+// foldPrefix returns how many bytes at the start of s are the same as match regardless of case, or -1 if s doesnt
+// start with match. It can differ from len(match), eg K and the kelvin sign are different lengths.
+func foldPrefix(s string, match string) int {
+	i := 0
+	for _, want := range match {
+		if i >= len(s) {
+			return -1
+		}
+		got, w := rune(s[i]), 1
+		if got < utf8.RuneSelf && want < utf8.RuneSelf {
+			// ascii letters only fold to each other
+			if got != want && lowerASCII(got) != lowerASCII(want) {
+				return -1
+			}
+			i++
+			continue
+		}
+		if got >= utf8.RuneSelf {
+			got, w = utf8.DecodeRuneInString(s[i:])
+		}
+		if got != want && foldRune(got) != foldRune(want) {
+			return -1
+		}
+		i += w
+	}
+	return i
+}
+
+func lowerASCII(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
+
 func parseRepetition(defaultMin, defaultMax int, repetition ...int) (min int, max int) {
 	min = defaultMin
 	max = defaultMax
@@ -178,10 +526,11 @@ func parseRepetition(defaultMin, defaultMax int, repetition ...int) (min int, ma
 	return min, max
 }
 
-// parseMatcher turns a string in the format a-f01234A-F into:
+// parseMatcher turns a string in the format a-f01234A-F\p{Greek} into:
 //   - an alphabet of matches string(01234)
 //   - a set of ranges [][]rune{{'a', 'f'}, {'A', 'F'}}
-func parseMatcher(matcher string) (alphabet string, ranges [][]rune) {
+//   - a set of unicode classes []unicodeClass{{unicode.Greek, false}}
+func parseMatcher(matcher string) (alphabet string, ranges [][]rune, classes []unicodeClass) {
 	runes := []rune(matcher)
 	for i := 0; i < len(runes); {
 		if i+2 < len(runes) && runes[i+1] == '-' && runes[i] != '\\' {
@@ -194,6 +543,16 @@ func parseMatcher(matcher string) (alphabet string, ranges [][]rune) {
 			}
 			i += 3 // we just consumed 3 bytes: range start, hyphen, and range end
 			continue
+		} else if i+2 < len(runes) && runes[i] == '\\' && (runes[i+1] == 'p' || runes[i+1] == 'P') && runes[i+2] == '{' {
+			end := i + 3
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end == len(runes) {
+				panic(fmt.Errorf("unterminated unicode class in %q", matcher))
+			}
+			classes = append(classes, lookupUnicodeClass(string(runes[i+3:end]), runes[i+1] == 'P'))
+			i = end + 1 // we just consumed the whole \p{Name}
 		} else if i+1 < len(runes) && runes[i] == '\\' {
 			alphabet += string(runes[i+1])
 			i += 2 // we just consumed 2 bytes: escape and the char
@@ -203,30 +562,41 @@ func parseMatcher(matcher string) (alphabet string, ranges [][]rune) {
 		}
 	}
 
-	return alphabet, ranges
+	return alphabet, ranges, classes
 }
 
 // Chars is the swiss army knife of character matches. It can match:
 //   - ranges: Chars("a-z") will match one or more lowercase letter
 //   - alphabets: Chars("abcd") will match one or more of the letters abcd in any order
+//   - unicode classes: Chars("\\p{L}") will match one or more letters, in any script. Categories like L or Nd,
+//     scripts like Greek and properties like White_Space can be used, \\P{Greek} is anything that isnt Greek.
+//     Unlike regexp the name always has to be in braces, \\pL is a p and an L.
 //   - min and max: Chars("a-z0-9", 4, 6) will match 4-6 lowercase alphanumeric characters
 //
-// the above can be combined in any order
+// the above can be combined in any order. Wrapping a matcher in [^ ] negates it, so Chars("[^0-9]") matches
+// anything but digits. Anywhere else ^, [ and ] are just characters.
 func Chars(matcher string, repetition ...int) Parser {
-	return NewParser("["+matcher+"]", charsImpl(matcher, false, repetition...))
+	return charsImpl(GrammarChars, "["+matcher+"]", matcher, false, repetition...)
 }
 
 // NotChars accepts the full range of input from Chars, but it will stop when any
 // character matches. If you need to match until you see a sequence use Until instead
 func NotChars(matcher string, repetition ...int) Parser {
-	return NewParser("!["+matcher+"]", charsImpl(matcher, true, repetition...))
+	return charsImpl(GrammarNotChars, "!["+matcher+"]", matcher, true, repetition...)
 }
 
-func charsImpl(matcher string, stopOn bool, repetition ...int) Parser {
+func charsImpl(kind GrammarKind, name string, matcher string, stopOn bool, repetition ...int) Parser {
 	min, max := parseRepetition(1, -1, repetition...)
-	alphabet, ranges := parseMatcher(matcher)
+	class := newCharClass(matcher)
 
-	return func(ps *State, node *Result) {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: kind, Name: name, Literal: matcher, Min: min, Max: max, contains: class.contains, ranges: class.candidates}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
+		startpos := ps.Pos
 		ps.WS(ps)
 		matched := 0
 		for ps.Pos+matched < len(ps.Input) {
@@ -234,26 +604,24 @@ func charsImpl(matcher string, stopOn bool, repetition ...int) Parser {
 				break
 			}
 
-			r, w := utf8.DecodeRuneInString(ps.Input[ps.Pos+matched:])
-
-			anyMatched := strings.ContainsRune(alphabet, r)
-			if !anyMatched {
-				for _, rng := range ranges {
-					if r >= rng[0] && r <= rng[1] {
-						anyMatched = true
-					}
+			if c := ps.Input[ps.Pos+matched]; c < utf8.RuneSelf {
+				if class.containsByte(c) == stopOn {
+					break
 				}
+				matched++
+				continue
 			}
 
-			if anyMatched == stopOn {
+			r, w := utf8.DecodeRuneInString(ps.Input[ps.Pos+matched:])
+			if class.contains(r) == stopOn {
 				break
 			}
-
 			matched += w
 		}
 
 		if matched < min {
 			ps.ErrorHere(matcher)
+			ps.Pos = startpos
 			return
 		}
 
@@ -261,14 +629,20 @@ func charsImpl(matcher string, stopOn bool, repetition ...int) Parser {
 		node.End = ps.Pos + matched
 		node.Token = ps.Input[ps.Pos : ps.Pos+matched]
 		ps.Advance(matched)
-	}
+	})
 }
 
 // Until will consume all input until one of the given terminator sequences is found. If you want to stop when seeing
 // single characters see NotChars instead
 func Until(terminators ...string) Parser {
 
-	return NewParser("Until", func(ps *State, node *Result) {
+	g := grammarFunc(func() *Grammar {
+		return &Grammar{Kind: GrammarUntil, Name: "Until", Terminators: terminators}
+	})
+	return newParser(g, func(ps *State, node *Result) {
+		if ps.Tracer != nil {
+			defer g.enter(ps, node).leave()
+		}
 		startPos := ps.Pos
 	loop:
 		for ps.Pos < len(ps.Input) {
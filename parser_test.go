@@ -1,7 +1,11 @@
 package goparsify
 
 import (
+	"math/rand"
+	"regexp"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/require"
 )
@@ -75,6 +79,12 @@ func TestExact(t *testing.T) {
 		require.Equal(t, 0, ps.Pos)
 	})
 
+	t.Run("error after whitespace", func(t *testing.T) {
+		_, ps := runParser("  foobar", Exact("bar"))
+		require.Equal(t, "offset 2: expected bar", ps.Error.Error())
+		require.Equal(t, 0, ps.Pos)
+	})
+
 	t.Run("eof char", func(t *testing.T) {
 		_, ps := runParser("", Exact("o"))
 		require.Equal(t, "o", ps.Error.expected)
@@ -82,6 +92,59 @@ func TestExact(t *testing.T) {
 	})
 }
 
+func TestExactFold(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		node, ps := runParser("SeLeCt *", ExactFold("select"))
+		require.Equal(t, "SeLeCt", node.Token)
+		require.Equal(t, 0, node.Start)
+		require.Equal(t, 6, node.End)
+		require.Equal(t, " *", ps.Get())
+	})
+
+	t.Run("success char", func(t *testing.T) {
+		node, ps := runParser("Xy", ExactFold("x"))
+		require.Equal(t, "X", node.Token)
+		require.Equal(t, "y", ps.Get())
+	})
+
+	t.Run("unicode", func(t *testing.T) {
+		node, ps := runParser("ΣΑΣ!", ExactFold("σας"))
+		require.Equal(t, "ΣΑΣ", node.Token)
+		require.Equal(t, "!", ps.Get())
+
+		// the kelvin sign and long s are longer than the letters they fold to
+		node, ps = runParser("\u212aiſs", ExactFold("KISS"))
+		require.Equal(t, "\u212aiſs", node.Token)
+		require.Equal(t, "", ps.Get())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, ps := runParser("  selec", ExactFold("select"))
+		require.Equal(t, "offset 2: expected select", ps.Error.Error())
+		require.Equal(t, 0, ps.Pos)
+
+		_, ps = runParser("sElEkt", ExactFold("select"))
+		require.True(t, ps.Errored())
+		require.Equal(t, 0, ps.Pos)
+	})
+}
+
+func TestFoldCase(t *testing.T) {
+	query := FoldCase(Seq("select", Chars("a-z*"), "from", Chars("a-z"), ";"))
+
+	t.Run("folds strings and Exact", func(t *testing.T) {
+		result, err := Parse(query, "SELECT * From users;")
+		require.NoError(t, err)
+		require.Equal(t, "SELECT", result.Child[0].Token)
+		require.Equal(t, "From", result.Child[2].Token)
+	})
+
+	t.Run("doesnt fold outside", func(t *testing.T) {
+		_, err := Parse(Seq(FoldCase("a"), "b"), "AB")
+		require.Equal(t, "offset 1: expected b", err.Error())
+	})
+}
+
 func TestChars(t *testing.T) {
 	t.Run("full match", func(t *testing.T) {
 		node, ps := runParser("foobar", Chars("a-z"))
@@ -118,6 +181,31 @@ func TestChars(t *testing.T) {
 		require.False(t, ps.Errored())
 	})
 
+	t.Run("unicode classes", func(t *testing.T) {
+		node, ps := runParser("Ωmega_δ2 = 1", Chars(`\p{L}\p{Nd}_`))
+		require.Equal(t, "Ωmega_δ2", node.Token)
+		require.Equal(t, " = 1", ps.Get())
+		require.False(t, ps.Errored())
+	})
+
+	t.Run("negated", func(t *testing.T) {
+		node, ps := runParser("αβγ abc", Chars(`[^\p{Latin}]`))
+		require.Equal(t, "αβγ ", node.Token)
+		require.Equal(t, "abc", ps.Get())
+		require.False(t, ps.Errored())
+
+		node, ps = runParser("a1b2]", Chars("[^0-9]", 1, 1))
+		require.Equal(t, "a", node.Token)
+		require.False(t, ps.Errored())
+	})
+
+	t.Run("leading caret", func(t *testing.T) {
+		node, ps := runParser("^_^_ab", Chars("^_"))
+		require.Equal(t, "^_^_", node.Token)
+		require.Equal(t, "ab", ps.Get())
+		require.False(t, ps.Errored())
+	})
+
 	t.Run("no match", func(t *testing.T) {
 		_, ps := runParser("ffffff", Chars("0-9"))
 		require.Equal(t, "offset 0: expected 0-9", ps.Error.Error())
@@ -175,6 +263,98 @@ func TestRegex(t *testing.T) {
 		require.Equal(t, "offset 0: expected [a-z]*", ps.Error.Error())
 		require.Equal(t, 0, ps.Pos)
 	})
+
+	t.Run("alternation is anchored", func(t *testing.T) {
+		node, ps := runParser("b a", Regex("a|b"))
+		require.Equal(t, "b", node.Token)
+		require.Equal(t, " a", ps.Get())
+
+		_, ps = runParser("xb", Regex("a|b"))
+		require.Equal(t, "offset 0: expected a|b", ps.Error.Error())
+		require.Equal(t, 0, ps.Pos)
+	})
+
+	t.Run("literal prefix", func(t *testing.T) {
+		node, ps := runParser("  0x1F", Regex("0x[0-9A-F]+"))
+		require.Equal(t, "0x1F", node.Token)
+		require.Equal(t, 2, node.Start)
+		require.Equal(t, "", ps.Get())
+
+		_, ps = runParser("  0b1", Regex("0x[0-9A-F]+"))
+		require.Equal(t, "offset 2: expected 0x[0-9A-F]+", ps.Error.Error())
+		require.Equal(t, 0, ps.Pos)
+	})
+
+	t.Run("captures", func(t *testing.T) {
+		node, ps := runParser(" 12:30 pm", Regex(`(\d+):(\d+)( am)?`))
+		require.Equal(t, "12:30", node.Token)
+		require.Equal(t, " pm", ps.Get())
+		require.Len(t, node.Child, 3)
+		require.Equal(t, "12", node.Child[0].Token)
+		require.Equal(t, 1, node.Child[0].Start)
+		require.Equal(t, 3, node.Child[0].End)
+		require.Equal(t, "30", node.Child[1].Token)
+		require.Equal(t, "", node.Child[2].Token)
+		require.Equal(t, -1, node.Child[2].Start)
+		require.Nil(t, node.Result)
+	})
+
+	t.Run("named groups", func(t *testing.T) {
+		node, _ := runParser("2024-06", Regex(`(?P<year>\d{4})-(?P<month>\d\d)(?:-(?P<day>\d\d))?`))
+		require.Equal(t, map[string]string{"year": "2024", "month": "06"}, node.Result)
+		require.Equal(t, "06", node.First("month").Token)
+		require.Equal(t, -1, node.First("day").Start)
+	})
+
+	t.Run("matches the same on long inputs", func(t *testing.T) {
+		patterns := []string{`\d{4}-\d\d`, `a|ab|abc`, `(?i)select\b`, `ab$`, `é{2}\b`, `(?m)a$`, `[a-c]{1,3}(x|yz)?`}
+		pieces := []string{"a", "b", "c", "x", "yz", "é", "1", "-", " ", "\n", "SELECT"}
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 2000; i++ {
+			pattern := patterns[r.Intn(len(patterns))]
+			// some are shorter than the window, some longer
+			input := &strings.Builder{}
+			for n := r.Intn(40); input.Len() < n; {
+				input.WriteString(pieces[r.Intn(len(pieces))])
+			}
+
+			expected := regexp.MustCompile("^(?:" + pattern + ")").FindString(input.String())
+			node, ps := runParser(input.String(), NoAutoWS(Regex(pattern)))
+			require.Equal(t, expected, node.Token, "%s on %q", pattern, input.String())
+			require.Equal(t, expected == "", ps.Errored())
+		}
+	})
+
+	t.Run("window", func(t *testing.T) {
+		require.Equal(t, 0, regexWindow(`a+`))
+		require.Equal(t, 0, regexWindow(`a{2,}`))
+		require.Equal(t, 0, regexWindow(`(a{100}){100}`))
+		require.Equal(t, 4*utf8.UTFMax, regexWindow(`a|abc`))
+		require.Equal(t, 8*utf8.UTFMax, regexWindow(`^(\d{2}|x)-\d{4}\b$`))
+	})
+
+	t.Run("precompiled", func(t *testing.T) {
+		re := regexp.MustCompile(`(?i)select|insert`)
+		node, ps := runParser("INSERT into", RegexMatcher(re))
+		require.Equal(t, "INSERT", node.Token)
+		require.Equal(t, " into", ps.Get())
+		require.Equal(t, "(?i)select|insert", Describe(RegexMatcher(re)).Literal)
+
+		_, ps = runParser("into INSERT", RegexMatcher(re))
+		require.Equal(t, "offset 0: expected (?i)select|insert", ps.Error.Error())
+		require.Equal(t, 0, ps.Pos)
+
+		// the regexp is used as it is
+		longest := regexp.MustCompile(`a|ab`)
+		longest.Longest()
+		node, _ = runParser("ab", RegexMatcher(longest))
+		require.Equal(t, "ab", node.Token)
+		node, _ = runParser("ab", Regex(`a|ab`))
+		require.Equal(t, "a", node.Token)
+
+		node, _ = runParser("x12", Seq("x", RegexMatcher(regexp.MustCompile(`^(\d)(\d)`))))
+		require.Equal(t, "2", node.Child[1].Child[1].Token)
+	})
 }
 
 func TestParseString(t *testing.T) {
@@ -201,6 +381,118 @@ func TestParseString(t *testing.T) {
 	})
 }
 
+func TestParsePrefix(t *testing.T) {
+	point := Seq("(", NumberLit(), ",", NumberLit(), ")")
+
+	t.Run("leaves the rest", func(t *testing.T) {
+		result, end, err := ParsePrefix(point, "(1, 2) and more", 0)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), result.Child[3].Result)
+		require.Equal(t, 6, end)
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		input := "from (1, 2) to (3,4)!"
+		result, end, err := ParsePrefix(point, input, 14)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), result.Child[1].Result)
+		require.Equal(t, "!", input[end:])
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, end, err := ParsePrefix(point, "x (1, 2", 2)
+		require.Equal(t, "offset 7: expected )", err.Error())
+		require.Equal(t, 2, end)
+	})
+
+	t.Run("whitespace", func(t *testing.T) {
+		_, _, err := ParsePrefix(point, " (1,2)", 0, NoWhitespace)
+		require.Equal(t, "offset 0: expected (", err.Error())
+	})
+
+	t.Run("offset before the input", func(t *testing.T) {
+		result, end, err := ParsePrefix(point, "(1, 2)", -1)
+		require.Equal(t, "offset -1 is outside of the 6 byte input", err.Error())
+		require.Nil(t, result)
+		require.Equal(t, -1, end)
+	})
+
+	t.Run("offset after the input", func(t *testing.T) {
+		result, end, err := ParsePrefix(point, "(1, 2)", 7)
+		require.Equal(t, "offset 7 is outside of the 6 byte input", err.Error())
+		require.Nil(t, result)
+		require.Equal(t, 7, end)
+
+		// the end of the input is still inside it
+		_, end, err = ParsePrefix(Maybe(point), "(1, 2)", 6)
+		require.NoError(t, err)
+		require.Equal(t, 6, end)
+	})
+}
+
+func TestScan(t *testing.T) {
+	tokens := func(results []*Result) []string {
+		found := []string{}
+		for _, result := range results {
+			found = append(found, result.Input[result.Start:result.End])
+		}
+		return found
+	}
+
+	t.Run("finds all", func(t *testing.T) {
+		assignment := Seq(Chars("a-z"), "=", NumberLit())
+		results := Scan(assignment, "set x=1 and y = 2, but not z=, then ab=3", -1)
+		require.Equal(t, []string{"x=1", "y = 2", "ab=3"}, tokens(results))
+		require.Equal(t, "y", results[1].Child[0].Token)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		require.Equal(t, []string{"1", "2"}, tokens(Scan(NumberLit(), "1 2 3", 2)))
+		require.Empty(t, Scan(NumberLit(), "1 2 3", 0))
+	})
+
+	t.Run("unicode", func(t *testing.T) {
+		require.Equal(t, []string{"été", "déjà"}, tokens(Scan(Chars(`\p{L}`), "été, déjà!", -1)))
+	})
+
+	t.Run("empty matches", func(t *testing.T) {
+		require.Len(t, Scan(Maybe("a"), "ba", -1), 3)
+	})
+
+	t.Run("matches trying every position", func(t *testing.T) {
+		parsers := []Parser{
+			Seq(Chars("a-z"), "=", NumberLit()),
+			Any(StringLit(`"`), "null", Seq("<", Chars("a-z"), ">")),
+			FoldCase("select"),
+			Chars("0-9", 2, 2),
+		}
+		for _, input := range []string{"a=1 b=2", `x "quoted" null <tag> <no`, "SELECT sElEcT selec", "12345 6 789", "é1 2é"} {
+			for _, parser := range parsers {
+				expected := []string{}
+				for pos := 0; pos <= len(input); {
+					ws := NewState(input)
+					ws.Pos = pos
+					UnicodeWhitespace(ws)
+					pos = ws.Pos
+
+					result, end, err := ParsePrefix(parser, input, pos)
+					switch {
+					case err != nil:
+						pos += runeWidth(input, pos)
+					case end > pos:
+						expected = append(expected, input[result.Start:result.End])
+						pos = end
+					default:
+						expected = append(expected, "")
+						pos += runeWidth(input, pos)
+					}
+				}
+				require.Equal(t, expected, tokens(Scan(parser, input, -1)), input)
+			}
+		}
+	})
+}
+
 func TestAutoWS(t *testing.T) {
 	t.Run("ws is not automatically consumed", func(t *testing.T) {
 		_, ps := runParser(" hello", NoAutoWS("hello"))
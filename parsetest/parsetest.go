@@ -0,0 +1,226 @@
+// Package parsetest runs a parser over a directory of input files and compares the results to golden files,
+// replacing long lists of near identical Run and require.Equal blocks.
+//
+//	func TestGrammar(t *testing.T) {
+//		parsetest.RunDir(t, root, "testdata", parsetest.Options{Format: parsetest.SExpr})
+//	}
+//
+// Each input file foo.txt is compared to foo.txt.golden. Run the tests with -update to write the golden files.
+package parsetest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ajitid/goparsify"
+)
+
+func init() {
+	// another golden file library may have already claimed -update, in which case we share it
+	if flag.Lookup("update") == nil {
+		flag.Bool("update", false, "update the golden files used by parsetest")
+	}
+}
+
+func updating() bool {
+	f := flag.Lookup("update")
+	return f != nil && f.Value.String() == "true"
+}
+
+// GoldenExt is appended to the name of an input file to get the name of its golden file
+const GoldenExt = ".golden"
+
+// Format is how a Result is written to a golden file
+type Format int
+
+const (
+	// String writes the Result using Result.String, the same format used in debug logs
+	String Format = iota
+	// SExpr writes the whole Result tree as an S-expression, with the span of every node
+	SExpr
+)
+
+// Options configure how RunDir parses and serializes each input
+type Options struct {
+	Format Format
+	// WS is the whitespace parser to use, defaults to UnicodeWhitespace like Run
+	WS goparsify.VoidParser
+}
+
+// RunDir parses every file in dir, except golden files, as a subtest and compares the serialized result or error
+// to the file's golden file. Subdirectories are ignored.
+func RunDir(t *testing.T, parser goparsify.Parserish, dir string, opts Options) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inputs []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), GoldenExt) {
+			continue
+		}
+		inputs = append(inputs, entry.Name())
+	}
+	sort.Strings(inputs)
+
+	if len(inputs) == 0 {
+		t.Fatalf("no input files found in %s", dir)
+	}
+
+	p := goparsify.Parsify(parser)
+	for _, name := range inputs {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := Serialize(p, string(input), opts)
+			golden := filepath.Join(dir, name+GoldenExt)
+
+			if updating() {
+				if err := os.WriteFile(golden, []byte(actual), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%s, run with -update to create it", err)
+			}
+			if string(expected) != actual {
+				t.Errorf("result does not match %s, run with -update if this is expected\n--- expected\n%s\n--- actual\n%s", golden, expected, actual)
+			}
+		})
+	}
+}
+
+// Serialize parses input the same way Run does and returns the result, or the error with its location, in
+// the given format. This is what RunDir writes to golden files.
+func Serialize(parser goparsify.Parserish, input string, opts Options) string {
+	var ws []goparsify.VoidParser
+	if opts.WS != nil {
+		ws = append(ws, opts.WS)
+	}
+	result, err := goparsify.Parse(parser, input, ws...)
+
+	buf := &strings.Builder{}
+	var unparsed goparsify.UnparsedInputError
+	switch {
+	case errors.As(err, &unparsed):
+		fmt.Fprintf(buf, "offset %d: %s\n", len(input)-len(unparsed.Remaining), unparsed.Error())
+	case err != nil:
+		return FormatError(err, input)
+	}
+
+	switch opts.Format {
+	case SExpr:
+		writeSExpr(buf, *result, true, 0)
+	default:
+		buf.WriteString(result.String())
+	}
+	buf.WriteByte('\n')
+	return buf.String()
+}
+
+// FormatError formats an error returned by Run for a golden file, including where in the input it happened
+func FormatError(err error, input string) string {
+	var perr *goparsify.Error
+	if errors.As(err, &perr) {
+		return perr.LocateError(input)
+	}
+	return err.Error() + "\n"
+}
+
+// SExprOf writes a Result tree as an S-expression. Each node is written as its label, kind and span, followed by
+// its token and result if they are set, followed by its children:
+//
+//	(0:12
+//	  (greeting 0:5 "hello")
+//	  (:noun 6:12 "world" 42))
+func SExprOf(r goparsify.Result) string {
+	buf := &strings.Builder{}
+	writeSExpr(buf, r, true, 0)
+	return buf.String()
+}
+
+// RoundTrip parses input, prints the result with printer and parses that again, failing the test unless both
+// parses give the same tree. Spans are not compared, as printing is free to move things around. It returns
+// the printed text.
+func RoundTrip(t *testing.T, parser goparsify.Parserish, printer *goparsify.Printer, input string, ws ...goparsify.VoidParser) string {
+	t.Helper()
+
+	printed, err := roundTrip(parser, printer, input, ws...)
+	if err != nil {
+		t.Error(err)
+	}
+	return printed
+}
+
+func roundTrip(parser goparsify.Parserish, printer *goparsify.Printer, input string, ws ...goparsify.VoidParser) (string, error) {
+	parsed, err := goparsify.Parse(parser, input, ws...)
+	if err != nil {
+		return "", fmt.Errorf("parsing input: %s", FormatError(err, input))
+	}
+
+	printed := printer.Print(parsed)
+	reparsed, err := goparsify.Parse(parser, printed, ws...)
+	if err != nil {
+		return printed, fmt.Errorf("parsing printed text: %s--- printed\n%s", FormatError(err, printed), printed)
+	}
+
+	expected := &strings.Builder{}
+	writeSExpr(expected, *parsed, false, 0)
+	actual := &strings.Builder{}
+	writeSExpr(actual, *reparsed, false, 0)
+	if expected.String() != actual.String() {
+		return printed, fmt.Errorf("printed text parses differently\n--- printed\n%s\n--- expected\n%s\n--- actual\n%s", printed, expected, actual)
+	}
+	return printed, nil
+}
+
+func writeSExpr(buf *strings.Builder, r goparsify.Result, spans bool, depth int) {
+	buf.WriteByte('(')
+	if r.Label != "" {
+		buf.WriteString(r.Label)
+	}
+	if r.Kind != "" {
+		buf.WriteByte(':')
+		buf.WriteString(r.Kind)
+	}
+	if spans {
+		if r.Label != "" || r.Kind != "" {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(buf, "%d:%d", r.Start, r.End)
+	}
+	if r.Token != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.Quote(r.Token))
+	}
+	if r.Result != nil {
+		if s, ok := r.Result.(fmt.Stringer); ok {
+			fmt.Fprintf(buf, " %s", s.String())
+		} else {
+			fmt.Fprintf(buf, " %#v", r.Result)
+		}
+	}
+	for _, child := range r.Child {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.Repeat("  ", depth+1))
+		writeSExpr(buf, child, spans, depth+1)
+	}
+	buf.WriteByte(')')
+}
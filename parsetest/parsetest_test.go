@@ -0,0 +1,77 @@
+package parsetest
+
+import (
+	"testing"
+
+	. "github.com/ajitid/goparsify"
+	"github.com/stretchr/testify/require"
+)
+
+var pairs = ZeroOrMore(Seq(Chars("a-z"), "=", Cut(), Any(StringLit(`"`), NumberLit())), ",")
+
+func TestRunDir(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		RunDir(t, pairs, "testdata/string", Options{})
+	})
+	t.Run("sexpr", func(t *testing.T) {
+		RunDir(t, pairs, "testdata/sexpr", Options{Format: SExpr})
+	})
+}
+
+func TestSerialize(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		require.Equal(t, "a\n", Serialize(Chars("a-z"), "a", Options{}))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		require.Equal(t, "Parsing error in line 1:\n1\n^\noffset 0: expected a-z\n", Serialize(Chars("a-z"), "1", Options{}))
+	})
+
+	t.Run("unparsed", func(t *testing.T) {
+		require.Equal(t, "offset 2: left unparsed: b\n(0:1 \"a\")\n", Serialize(Exact("a"), "a b", Options{Format: SExpr}))
+	})
+
+	t.Run("whitespace", func(t *testing.T) {
+		require.Equal(t, "offset 0: left unparsed:  a\n\n", Serialize(Maybe("a"), " a", Options{WS: NoWhitespace}))
+	})
+}
+
+func TestSExprOf(t *testing.T) {
+	result := Result{Start: 0, End: 12, Child: []Result{
+		{Start: 0, End: 5, Token: "hello", Label: "greeting"},
+		{Start: 6, End: 12, Token: "wor\"ld", Result: 42, Kind: "noun"},
+	}}
+	require.Equal(t, "(0:12\n  (greeting 0:5 \"hello\")\n  (:noun 6:12 \"wor\\\"ld\" 42))", SExprOf(result))
+}
+
+func TestFormatError(t *testing.T) {
+	_, err := Run("a", "b")
+	require.Equal(t, "Parsing error in line 1:\nb\n^\noffset 0: expected a\n", FormatError(err, "b"))
+
+	_, err = Run("a", "a b")
+	require.Equal(t, "left unparsed: b\n", FormatError(err, "a b"))
+}
+
+func TestRoundTrip(t *testing.T) {
+	printer := &Printer{
+		Hints: map[string]Hint{"pairs": {Separator: ",", Between: LayoutLine}},
+		Tokens: map[string]func(n *Result) string{
+			"string": func(n *Result) string { return `"` + n.Token + `"` },
+		},
+	}
+	kinded := WithKind("pairs", ZeroOrMore(Seq(Chars("a-z"), "=", Cut(), Any(WithKind("string", StringLit(`"`)), NumberLit())), ","))
+
+	require.Equal(t, "name=\"gopher\",\nage=7", RoundTrip(t, kinded, printer, `name = "gopher", age = 7`))
+
+	t.Run("parses differently", func(t *testing.T) {
+		// without its separator the printed text is a single pair
+		_, err := roundTrip(kinded, &Printer{}, `a = 1, b = 2`)
+		require.Error(t, err)
+	})
+
+	t.Run("cant parse printed text", func(t *testing.T) {
+		_, err := roundTrip(kinded, &Printer{}, `a = "x"`)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parsing printed text")
+	})
+}
@@ -1,6 +1,9 @@
 package goparsify
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func BenchmarkAny(b *testing.B) {
 	p := Any("hello", "goodbye", "help")
@@ -13,3 +16,165 @@ func BenchmarkAny(b *testing.B) {
 		_, _ = Run(p, "help me")
 	}
 }
+
+var operators = []string{"<<=", ">>=", "&^=", "...", "&&", "||", "<-", "++", "--", "==", "!=", "<=", ">=", ":=",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<", ">>", "&^", "+", "-", "*", "/", "%", "&", "|", "^", "<", ">",
+	"=", "!", "(", ")", "[", "]", "{", "}", ",", ";", ".", ":", "~"}
+
+func BenchmarkOperatorsAny(b *testing.B) {
+	// Any needs the longest operators first
+	alternatives := make([]Parserish, len(operators))
+	for i, op := range operators {
+		alternatives[i] = op
+	}
+	p := ZeroOrMore(Any(alternatives...))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, "<<= ... && ~ := } ; != ^ >")
+	}
+}
+
+func BenchmarkOperatorsOneOf(b *testing.B) {
+	p := ZeroOrMore(OneOf(operators...))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, "<<= ... && ~ := } ; != ^ >")
+	}
+}
+
+var (
+	identifierInput = strings.Repeat("some_identifier_123", 50)
+	unicodeInput    = strings.Repeat("ünïcödé_ïdéntïfïér", 50)
+	textInput       = strings.Repeat("plain text with no tags in it, ", 50) + "<"
+)
+
+func BenchmarkCharsASCII(b *testing.B) {
+	p := Chars("a-zA-Z0-9_")
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, identifierInput, ASCIIWhitespace)
+	}
+}
+
+func BenchmarkCharsUnicode(b *testing.B) {
+	p := Chars("a-zA-Z0-9_à-ÿ")
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, unicodeInput)
+	}
+}
+
+func BenchmarkNotChars(b *testing.B) {
+	p := Seq(NotChars("<>"), "<")
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, textInput)
+	}
+}
+
+func BenchmarkCharsUnicodeClass(b *testing.B) {
+	p := Chars(`\p{L}\p{Nd}_`)
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, unicodeInput)
+	}
+}
+
+func BenchmarkRegexUnicodeClass(b *testing.B) {
+	p := Regex(`[\p{L}\p{Nd}_]+`)
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, unicodeInput)
+	}
+}
+
+func BenchmarkRegexLongInput(b *testing.B) {
+	// every match sees the rest of the input
+	p := ZeroOrMore(Seq(Regex(`\d{1,4}-\d\d?-\d\d?`), Chars("a-z")))
+	input := strings.Repeat("2024-06-01 ok ", 1000)
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, input)
+	}
+}
+
+// a statement that needs backtracking, as assignments and calls both start with an identifier
+var statementInput = strings.Repeat("total = total + price * 2; // running total\nprint(total, \"total\");\n", 20)
+
+func BenchmarkStatementsChars(b *testing.B) {
+	var expr Parser
+	atom := Any(NumberLit(), Chars("a-z"), StringLit(`"`), Seq("(", &expr, ")"))
+	expr = Seq(atom, ZeroOrMore(Seq(OneOf("+", "*"), atom)))
+	ws := func(s *State) {
+		for {
+			UnicodeWhitespace(s)
+			if !strings.HasPrefix(s.Get(), "//") {
+				return
+			}
+			if end := strings.IndexByte(s.Get(), '\n'); end >= 0 {
+				s.Advance(end)
+			} else {
+				s.Pos = len(s.Input)
+			}
+		}
+	}
+	statement := Any(Seq(Chars("a-z"), "=", &expr, ";"), Seq(Chars("a-z"), "(", ZeroOrMore(&expr, ","), ")", ";"))
+	p := ZeroOrMore(statement)
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, statementInput, ws)
+	}
+}
+
+func BenchmarkStatementsLexer(b *testing.B) {
+	lexer := NewLexer(
+		TokenRule{Kind: "space", Parser: Chars(" \t\n"), Skip: true},
+		TokenRule{Kind: "comment", Parser: Seq("//", NotChars("\n", 0)), Skip: true},
+		TokenRule{Kind: "ident", Parser: Chars("a-z")},
+		TokenRule{Kind: "number", Parser: NumberLit()},
+		TokenRule{Kind: "string", Parser: StringLit(`"`)},
+		TokenRule{Kind: "op", Parser: OneOf("+", "*", "=", "(", ")", ",", ";", "/")},
+	)
+	var expr Parser
+	atom := Any(Tok("number"), Tok("ident"), Tok("string"), Seq(Tok("op", "("), &expr, Tok("op", ")")))
+	expr = Seq(atom, ZeroOrMore(Seq(Tok("op", "+", "*"), atom)))
+	statement := Any(
+		Seq(Tok("ident"), Tok("op", "="), &expr, Tok("op", ";")),
+		Seq(Tok("ident"), Tok("op", "("), ZeroOrMore(&expr, Tok("op", ",")), Tok("op", ")"), Tok("op", ";")),
+	)
+	p := ZeroOrMore(statement)
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = lexer.Run(p, statementInput)
+	}
+}
+
+func BenchmarkChain(b *testing.B) {
+	// a new parser is created for every match
+	p := ZeroOrMore(Chain(Chars("a-z"), func(prevN *Result) Parserish { return Exact(prevN.Token) }))
+	input := strings.Repeat("abc abc ", 50)
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Run(p, input)
+	}
+}
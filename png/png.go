@@ -0,0 +1,65 @@
+// Package png is an example binary parser, it splits a PNG file into its chunks.
+package png
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	. "github.com/ajitid/goparsify"
+)
+
+// Chunk is one chunk of a PNG file
+type Chunk struct {
+	// Type is the chunk type, eg IHDR or IDAT
+	Type string
+	// Data points into the input given to Chunks, so it must not be changed while the chunk is in use
+	Data string
+}
+
+type chunk struct {
+	Chunk
+	// typeAndData is what the crc is of
+	typeAndData string
+	crc         uint32
+}
+
+var (
+	signature = Exact("\x89PNG\r\n\x1a\n")
+
+	// the length is only of the data, but the type comes before it, so take both at once. That is also exactly
+	// what the crc covers.
+	typeAndData = Take(Uint32(binary.BigEndian).Map(func(n *Result) { n.Result = uint64(n.Result.(uint32)) + 4 }))
+
+	pngChunk = Seq(typeAndData, Uint32(binary.BigEndian)).Map(func(n *Result) {
+		body := n.Child[0].Token
+		n.Result = chunk{Chunk: Chunk{Type: body[:4], Data: body[4:]}, typeAndData: body, crc: n.Child[1].Result.(uint32)}
+	})
+
+	file = Seq(signature, OneOrMore(pngChunk)).Map(func(n *Result) {
+		chunks := make([]chunk, 0, len(n.Child[1].Child))
+		for _, child := range n.Child[1].Child {
+			chunks = append(chunks, child.Result.(chunk))
+		}
+		n.Result = chunks
+	})
+
+	_chunks = NewRunner(file, RunOptions{WS: NoWhitespace})
+)
+
+// Chunks splits a PNG file into its chunks, checking the crc of each one
+func Chunks(input []byte) ([]Chunk, error) {
+	result, err := _chunks.RunBytes(input)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []Chunk{}
+	for _, c := range result.([]chunk) {
+		if crc32.ChecksumIEEE([]byte(c.typeAndData)) != c.crc {
+			return nil, fmt.Errorf("bad crc for %s chunk", c.Type)
+		}
+		ret = append(ret, c.Chunk)
+	}
+	return ret, nil
+}
@@ -0,0 +1,59 @@
+package png
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(1, 1, color.RGBA{R: 0xff, A: 0xff})
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, img))
+	return buf.Bytes()
+}
+
+func TestChunks(t *testing.T) {
+	input := encode(t)
+	chunks, err := Chunks(input)
+	require.NoError(t, err)
+
+	types := []string{}
+	for _, chunk := range chunks {
+		types = append(types, chunk.Type)
+	}
+	require.Equal(t, []string{"IHDR", "IDAT", "IEND"}, types)
+
+	// width and height
+	require.Equal(t, "\x00\x00\x00\x03\x00\x00\x00\x02", chunks[0].Data[:8])
+	require.Equal(t, "", chunks[2].Data)
+
+	// the data isnt copied
+	require.Equal(t, &input[16], unsafe.StringData(chunks[0].Data))
+}
+
+func TestChunksErrors(t *testing.T) {
+	t.Run("not a png", func(t *testing.T) {
+		_, err := Chunks([]byte("GIF89a"))
+		require.Equal(t, "offset 0: expected \x89PNG\r\n\x1a\n", err.Error())
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		input := encode(t)
+		_, err := Chunks(input[:20])
+		require.Equal(t, "offset 12: expected 17 bytes", err.Error())
+	})
+
+	t.Run("bad crc", func(t *testing.T) {
+		input := encode(t)
+		input[20]++
+		_, err := Chunks(input)
+		require.Equal(t, "bad crc for IHDR chunk", err.Error())
+	})
+}
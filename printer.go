@@ -0,0 +1,126 @@
+package goparsify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layout is the whitespace a Printer puts between two tokens
+type Layout int
+
+const (
+	// LayoutNone writes tokens right next to each other
+	LayoutNone Layout = iota
+	// LayoutSpace writes a single space between tokens
+	LayoutSpace
+	// LayoutLine starts a new, indented, line
+	LayoutLine
+)
+
+// Hint says how a Printer lays out nodes of one Kind. When hints ask for different layouts between the same two
+// tokens the biggest wins, eg a line break beats a space.
+type Hint struct {
+	// Before and After are the layout around the node
+	Before, After Layout
+	// Between is the layout between the children of the node, after the Separator
+	Between Layout
+	// Separator is written between the children of the node. Repetitions like ZeroOrMore dont return
+	// their separators unless told to keep them, so they need to be put back here.
+	Separator string
+	// Indent puts the children of the node on their own lines, one level deeper than the node
+	Indent bool
+}
+
+// Printer turns a Result tree back into text, the inverse of parsing. Give nodes a kind with WithKind so
+// they can be given a Hint or a token format.
+//
+// The tree can come from parsing, so reformatting a document, or be built by hand or by Walk to generate one.
+type Printer struct {
+	// Indent is written once per level of indentation, it defaults to two spaces
+	Indent string
+	// Hints are the layout of each Kind of node
+	Hints map[string]Hint
+	// Tokens format leaf nodes of a Kind, eg to quote strings. Nodes without a format are written as
+	// their .Token, or their .Result if they dont have a token. Nodes with a .Token are leaves even if they
+	// have children.
+	Tokens map[string]func(n *Result) string
+}
+
+// Print writes the tree rooted at n
+func (p *Printer) Print(n *Result) string {
+	pw := &printWriter{Printer: p, indent: p.Indent}
+	if pw.indent == "" {
+		pw.indent = "  "
+	}
+	pw.print(n)
+	return pw.buf.String()
+}
+
+type printWriter struct {
+	*Printer
+	buf     strings.Builder
+	indent  string
+	depth   int
+	pending Layout
+}
+
+func (pw *printWriter) print(n *Result) {
+	hint := pw.Hints[n.Kind]
+	pw.layout(hint.Before)
+
+	// a node with a token already has all of its text, its children are parts of it, eg Regex groups or Merge
+	if len(n.Child) == 0 || n.Token != "" {
+		pw.write(pw.token(n))
+	} else {
+		if hint.Indent {
+			pw.depth++
+			pw.layout(LayoutLine)
+		}
+		for i := range n.Child {
+			if i > 0 {
+				pw.write(hint.Separator)
+				pw.layout(hint.Between)
+			}
+			pw.print(&n.Child[i])
+		}
+		if hint.Indent {
+			pw.depth--
+			pw.layout(LayoutLine)
+		}
+	}
+
+	pw.layout(hint.After)
+}
+
+func (pw *printWriter) token(n *Result) string {
+	if format := pw.Tokens[n.Kind]; format != nil {
+		return format(n)
+	}
+	if n.Token == "" && n.Result != nil {
+		return fmt.Sprint(n.Result)
+	}
+	return n.Token
+}
+
+func (pw *printWriter) layout(l Layout) {
+	if l > pw.pending {
+		pw.pending = l
+	}
+}
+
+func (pw *printWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if pw.buf.Len() > 0 {
+		switch pw.pending {
+		case LayoutSpace:
+			pw.buf.WriteByte(' ')
+		case LayoutLine:
+			pw.buf.WriteByte('\n')
+			pw.buf.WriteString(strings.Repeat(pw.indent, pw.depth))
+		}
+	}
+	pw.pending = LayoutNone
+	pw.buf.WriteString(s)
+}
@@ -0,0 +1,71 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrinter(t *testing.T) {
+	var value Parser
+	list := Seq("(", WithKind("items", ZeroOrMore(&value, ",")), ")")
+	pair := Seq(Chars("a-z"), WithKind("eq", "="), NumberLit())
+	value = Any(WithKind("pair", pair), NumberLit(), WithKind("string", StringLit(`"`)), list)
+
+	parse := func(input string) *Result {
+		result, err := Parse(value, input)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("writes tokens without hints", func(t *testing.T) {
+		printer := &Printer{}
+		require.Equal(t, "(a=1(2))", printer.Print(parse("( a = 1, ( 2 ) )")))
+	})
+
+	t.Run("lays out with hints", func(t *testing.T) {
+		printer := &Printer{Hints: map[string]Hint{
+			"items": {Separator: ",", Between: LayoutSpace},
+			"eq":    {Before: LayoutSpace, After: LayoutSpace},
+		}}
+		require.Equal(t, "(a = 1, (2, 3))", printer.Print(parse("(a=1,(2,3))")))
+	})
+
+	t.Run("indents", func(t *testing.T) {
+		printer := &Printer{Indent: "\t", Hints: map[string]Hint{
+			"items": {Separator: ",", Between: LayoutLine, Indent: true},
+		}}
+		require.Equal(t, "(\n\t1,\n\t(\n\t\t2\n\t),\n\t()\n)", printer.Print(parse("(1,(2),())")))
+	})
+
+	t.Run("biggest layout wins", func(t *testing.T) {
+		printer := &Printer{Hints: map[string]Hint{
+			"items": {Separator: ",", Between: LayoutSpace},
+			"pair":  {Before: LayoutLine, After: LayoutSpace},
+		}}
+		require.Equal(t, "(1,\na=1 , 2)", printer.Print(parse("(1,a=1,2)")))
+	})
+
+	t.Run("formats tokens", func(t *testing.T) {
+		printer := &Printer{Tokens: map[string]func(n *Result) string{
+			"string": func(n *Result) string { return `"` + n.Token + `"` },
+		}}
+		require.Equal(t, `("hi"1.5)`, printer.Print(parse(`("hi", 1.5)`)))
+	})
+
+	t.Run("writes tokens over children", func(t *testing.T) {
+		result, err := Parse(Seq(Regex(`(\d+)\.(\d+)`), Merge(Seq("v", Chars("0-9")))), "1.25 v2")
+		require.NoError(t, err)
+		require.Equal(t, "1.25v2", (&Printer{}).Print(result))
+	})
+
+	t.Run("prints built trees", func(t *testing.T) {
+		printer := &Printer{Hints: map[string]Hint{"items": {Separator: ", "}}}
+		tree := &Result{Child: []Result{
+			{Token: "("},
+			{Kind: "items", Child: []Result{{Result: 1}, {Token: "x"}}},
+			{Token: ")"},
+		}}
+		require.Equal(t, "(1, x)", printer.Print(tree))
+	})
+}
@@ -0,0 +1,123 @@
+package goparsify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Named labels the result of parser so it can be found with Query, First and Find instead of by its index
+// in .Child, which changes whenever the grammar does.
+func Named(label string, parser Parserish) Parser {
+	p := Parsify(parser)
+
+	return wrapParser(p, func(ps *State, node *Result) {
+		p(ps, node)
+		if ps.Errored() {
+			return
+		}
+		node.Label = label
+	})
+}
+
+// Query returns the nodes matching a path of labels separated by slashes, eg tag/attrs/attr[0].
+//
+// Each label matches the nearest labeled descendants of the nodes matched so far, looking through unlabeled
+// nodes but not into nodes with a different label. * matches any label. A label may be followed by an index
+// to pick one of its matches under each parent, negative indexes count from the end.
+//
+// The returned nodes point into the tree, so they can be modified from a Map callback.
+// Query panics if the path is malformed.
+func (r *Result) Query(path string) []*Result {
+	nodes := []*Result{r}
+	for _, segment := range strings.Split(path, "/") {
+		label, index, hasIndex := parseSegment(path, segment)
+
+		var next []*Result
+		for _, node := range nodes {
+			var matches []*Result
+			for i := range node.Child {
+				matches = nearestLabeled(&node.Child[i], label, matches)
+			}
+
+			if !hasIndex {
+				next = append(next, matches...)
+				continue
+			}
+			i := index
+			if i < 0 {
+				i += len(matches)
+			}
+			if i >= 0 && i < len(matches) {
+				next = append(next, matches[i])
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// First returns the first node matching path, or nil if there isnt one. See Query for the path syntax.
+func (r *Result) First(path string) *Result {
+	nodes := r.Query(path)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
+
+// Find returns every descendant with the given label at any depth, in the order they were parsed
+func (r *Result) Find(label string) []*Result {
+	var found []*Result
+	r.Each(func(n *Result) bool {
+		if n != r && n.Label == label {
+			found = append(found, n)
+		}
+		return true
+	})
+	return found
+}
+
+// Each calls f for this node and then every descendant, depth first in the order they were parsed.
+// Returning false from f skips the children of that node.
+func (r *Result) Each(f func(n *Result) bool) {
+	if !f(r) {
+		return
+	}
+	for i := range r.Child {
+		r.Child[i].Each(f)
+	}
+}
+
+func nearestLabeled(n *Result, label string, matches []*Result) []*Result {
+	if n.Label != "" {
+		if label == "*" || n.Label == label {
+			matches = append(matches, n)
+		}
+		return matches
+	}
+	for i := range n.Child {
+		matches = nearestLabeled(&n.Child[i], label, matches)
+	}
+	return matches
+}
+
+func parseSegment(path string, segment string) (label string, index int, hasIndex bool) {
+	label = segment
+	if open := strings.IndexByte(segment, '['); open != -1 {
+		if !strings.HasSuffix(segment, "]") {
+			panic(fmt.Errorf("invalid result path %q: unterminated index in %q", path, segment))
+		}
+		var err error
+		index, err = strconv.Atoi(segment[open+1 : len(segment)-1])
+		if err != nil {
+			panic(fmt.Errorf("invalid result path %q: bad index in %q", path, segment))
+		}
+		label = segment[:open]
+		hasIndex = true
+	}
+	if label == "" {
+		panic(fmt.Errorf("invalid result path %q: empty label", path))
+	}
+	return label, index, hasIndex
+}
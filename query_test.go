@@ -0,0 +1,110 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamed(t *testing.T) {
+	t.Run("labels matches", func(t *testing.T) {
+		node, _ := runParser("hello", Named("greeting", "hello"))
+		require.Equal(t, "greeting", node.Label)
+		require.Equal(t, "hello", node.Token)
+	})
+
+	t.Run("doesnt label errors", func(t *testing.T) {
+		node, ps := runParser("world", Named("greeting", "hello"))
+		require.Equal(t, "", node.Label)
+		require.True(t, ps.Errored())
+	})
+
+	t.Run("labels the chosen branch", func(t *testing.T) {
+		node, _ := runParser("2", Any(Named("word", Chars("a-z")), Named("number", NumberLit())))
+		require.Equal(t, "number", node.Label)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	ident := Chars("a-z")
+	attr := Seq(Named("name", ident), "=", Named("value", StringLit(`"`)))
+	tag := Seq("<", Named("tag", ident), Named("attrs", ZeroOrMore(Named("attr", attr))), ">")
+	doc := OneOrMore(Named("tag", tag))
+
+	node, ps := runParser(`<a href="x" title="y"> <b class="z">`, doc)
+	require.False(t, ps.Errored())
+
+	t.Run("path", func(t *testing.T) {
+		require.Equal(t, "a", node.First("tag/tag").Token)
+		require.Equal(t, "x", node.First("tag/attrs/attr/value").Token)
+		require.Equal(t, "title", node.First("tag/attrs/attr[1]/name").Token)
+	})
+
+	t.Run("index applies under each parent", func(t *testing.T) {
+		values := []string{}
+		for _, n := range node.Query("tag/attrs/attr[-1]/value") {
+			values = append(values, n.Token)
+		}
+		require.Equal(t, []string{"y", "z"}, values)
+	})
+
+	t.Run("doesnt look inside other labels", func(t *testing.T) {
+		require.Empty(t, node.Query("attr"))
+		require.Nil(t, node.First("tag[5]"))
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		require.Len(t, node.Query("tag/*"), 4)
+		require.Len(t, node.Query("*/attrs/*"), 3)
+	})
+
+	t.Run("returns nodes in the tree", func(t *testing.T) {
+		copied := node
+		copied.Child = append([]Result{}, node.Child...)
+		copied.First("tag").Result = 42
+		require.Equal(t, 42, copied.Child[0].Result)
+	})
+
+	t.Run("panics on bad paths", func(t *testing.T) {
+		require.Panics(t, func() { node.Query("tag/") })
+		require.Panics(t, func() { node.Query("tag[x]") })
+		require.Panics(t, func() { node.Query("tag[0") })
+	})
+}
+
+func TestFind(t *testing.T) {
+	item := Named("item", Chars("a-z"))
+	list := Seq("(", Named("list", ZeroOrMore(item, ",")), ")")
+	node, ps := runParser("(a, b, c)!", Named("root", Seq(list, Named("item", "!"))))
+
+	require.False(t, ps.Errored())
+
+	tokens := []string{}
+	for _, n := range node.Find("item") {
+		tokens = append(tokens, n.Token)
+	}
+	require.Equal(t, []string{"a", "b", "c", "!"}, tokens)
+	require.Empty(t, node.Find("root"))
+}
+
+func TestEach(t *testing.T) {
+	node, _ := runParser("a b c", Seq("a", Seq("b", "c")))
+
+	t.Run("visits every node", func(t *testing.T) {
+		tokens := []string{}
+		node.Each(func(n *Result) bool {
+			tokens = append(tokens, n.Token)
+			return true
+		})
+		require.Equal(t, []string{"", "a", "", "b", "c"}, tokens)
+	})
+
+	t.Run("skips children", func(t *testing.T) {
+		count := 0
+		node.Each(func(n *Result) bool {
+			count++
+			return n.Token != "" || count == 1
+		})
+		require.Equal(t, 3, count)
+	})
+}
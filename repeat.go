@@ -0,0 +1,69 @@
+package goparsify
+
+import "fmt"
+
+// Trailing is what a repetition with a separator does with one after its last item
+type Trailing int
+
+const (
+	// TrailingNever only matches separators between items, so one after the last item is left for whatever
+	// comes next, eg to reject [1,2,]. If nothing does the error is an unexpected trailing separator.
+	TrailingNever Trailing = iota
+	// TrailingOptional matches one after the last item if it is there, like ZeroOrMore and OneOrMore do
+	TrailingOptional
+	// TrailingRequired needs one after every item, eg statements ending in ;
+	TrailingRequired
+)
+
+// RepeatOptions are how RepeatWith repeats a parser
+type RepeatOptions struct {
+	// Min and Max are how many items to match. A Max of 0 is no limit.
+	Min, Max int
+	// Separator is matched between the items, see Trailing
+	Separator Parserish
+	Trailing  Trailing
+	// KeepSeparators returns the separators in .Child between the items, instead of dropping them. Items are
+	// then every other child.
+	KeepSeparators bool
+}
+
+// RepeatWith matches parser as many times as opts allow and returns the values as .Child[n]. Like ZeroOrMore it
+// matches as many items as it can without backtracking to try fewer.
+func RepeatWith(parser Parserish, opts RepeatOptions) Parser {
+	if opts.Max == 0 {
+		opts.Max = -1
+	}
+	return repeatImpl("RepeatWith()", parser, opts)
+}
+
+// SepBy matches zero or more parsers with separator between them and returns the values as .Child[n]. Unlike
+// ZeroOrMore a separator after the last item isnt matched.
+func SepBy(parser Parserish, separator Parserish) Parser {
+	return repeatImpl("SepBy()", parser, RepeatOptions{Max: -1, Separator: separator, Trailing: TrailingNever})
+}
+
+// SepBy1 is SepBy for one or more parsers
+func SepBy1(parser Parserish, separator Parserish) Parser {
+	return repeatImpl("SepBy1()", parser, RepeatOptions{Min: 1, Max: -1, Separator: separator, Trailing: TrailingNever})
+}
+
+// SepEndBy is SepBy that also matches a separator after the last item if there is one
+func SepEndBy(parser Parserish, separator Parserish) Parser {
+	return repeatImpl("SepEndBy()", parser, RepeatOptions{Max: -1, Separator: separator, Trailing: TrailingOptional})
+}
+
+// EndBy matches zero or more parsers that are each followed by separator, and returns the values as .Child[n]
+func EndBy(parser Parserish, separator Parserish) Parser {
+	return repeatImpl("EndBy()", parser, RepeatOptions{Max: -1, Separator: separator, Trailing: TrailingRequired})
+}
+
+// Count matches parser exactly n times and returns the values as .Child[n]
+func Count(n int, parser Parserish) Parser {
+	return repeatImpl(fmt.Sprintf("Count(%d)", n), parser, RepeatOptions{Min: n, Max: n})
+}
+
+// Repeat matches parser at least min and at most max times and returns the values as .Child[n]. A max < 0 is
+// no limit.
+func Repeat(min, max int, parser Parserish) Parser {
+	return repeatImpl(fmt.Sprintf("Repeat(%d, %d)", min, max), parser, RepeatOptions{Min: min, Max: max})
+}
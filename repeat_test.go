@@ -0,0 +1,172 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSepBy(t *testing.T) {
+	list := SepBy(Chars("a-z"), ",")
+
+	t.Run("matches", func(t *testing.T) {
+		result, p := runParser("a, b ,c", list)
+		assertSequence(t, result, "a", "b", "c")
+		require.Equal(t, "", p.Get())
+
+		result, p = runParser("", list)
+		require.Len(t, result.Child, 0)
+		require.False(t, p.Errored())
+	})
+
+	t.Run("leaves a trailing separator", func(t *testing.T) {
+		result, p := runParser("a,b,", list)
+		assertSequence(t, result, "a", "b")
+		require.Equal(t, ",", p.Get())
+
+		// enough items for the children to have grown since the separator
+		result, p = runParser("a,b,c,d,e,f,g,h,i,j,k,", Seq(list, ","))
+		assertSequence(t, result.Child[0], "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k")
+		require.Equal(t, "", p.Get())
+
+		_, err := Run(Seq("[", list, "]"), "[a,b,]")
+		require.Equal(t, "offset 4: unexpected trailing separator", err.Error())
+
+		// unless something else matches it
+		_, err = Run(Seq("f(", list, Maybe(Seq(",", "...")), ")"), "f(a,b, ...)")
+		require.NoError(t, err)
+
+		_, err = Run(Seq("[", list, "]"), "[a,b]")
+		require.NoError(t, err)
+	})
+
+	t.Run("one or more", func(t *testing.T) {
+		result, p := runParser("a,b,", SepBy1(Chars("a-z"), ","))
+		assertSequence(t, result, "a", "b")
+		require.Equal(t, ",", p.Get())
+
+		_, p = runParser(",", SepBy1(Chars("a-z"), ","))
+		require.Equal(t, "offset 0: expected a-z", p.Error.Error())
+		require.Equal(t, 0, p.Pos)
+	})
+
+	t.Run("gives back what the separator matched", func(t *testing.T) {
+		parser := Seq(SepBy(Chars("a-z"), Capture("sep", Chars(",;"))), Backref("sep"))
+		_, err := Run(parser, "a,b,")
+		require.NoError(t, err)
+
+		// the last separator captured ; but wasnt matched in the end
+		_, err = Run(parser, "a,b;")
+		require.Equal(t, "offset 3: unexpected trailing separator", err.Error())
+	})
+}
+
+func TestSepEndBy(t *testing.T) {
+	result, p := runParser("a,b,", SepEndBy(Chars("a-z"), ","))
+	assertSequence(t, result, "a", "b")
+	require.Equal(t, "", p.Get())
+
+	result, p = runParser("a,b", SepEndBy(Chars("a-z"), ","))
+	assertSequence(t, result, "a", "b")
+	require.Equal(t, "", p.Get())
+}
+
+func TestEndBy(t *testing.T) {
+	statements := EndBy(Chars("a-z"), ";")
+
+	result, p := runParser("a; b;", statements)
+	assertSequence(t, result, "a", "b")
+	require.Equal(t, "", p.Get())
+
+	result, p = runParser("a; b", statements)
+	assertSequence(t, result, "a")
+	require.Equal(t, " b", p.Get())
+
+	_, err := Run(statements, "a; b")
+	require.Equal(t, "left unparsed: b", err.Error())
+
+	// a cut in an item commits it to its separator too
+	_, err = Run(EndBy(Seq("let", Cut(), Chars("a-z")), ";"), "let a; let b")
+	require.Equal(t, "offset 12: expected ;", err.Error())
+}
+
+func TestCount(t *testing.T) {
+	result, p := runParser("abcd", Count(3, Chars("a-z", 1, 1)))
+	assertSequence(t, result, "a", "b", "c")
+	require.Equal(t, "d", p.Get())
+
+	_, p = runParser("ab", Count(3, Chars("a-z", 1, 1)))
+	require.Equal(t, "offset 2: expected a-z", p.Error.Error())
+	require.Equal(t, 0, p.Pos)
+
+	result, p = runParser("ab", Count(0, Chars("a-z", 1, 1)))
+	require.Len(t, result.Child, 0)
+	require.Equal(t, "ab", p.Get())
+
+	// items that match nothing are still counted
+	result, p = runParser("x", Count(2, Maybe("a")))
+	require.Len(t, result.Child, 2)
+	require.False(t, p.Errored())
+}
+
+func TestRepeat(t *testing.T) {
+	digits := Repeat(2, 4, Chars("0-9", 1, 1))
+
+	result, p := runParser("123456", digits)
+	assertSequence(t, result, "1", "2", "3", "4")
+	require.Equal(t, "56", p.Get())
+
+	result, _ = runParser("12", digits)
+	assertSequence(t, result, "1", "2")
+
+	_, p = runParser("1", digits)
+	require.Equal(t, "offset 1: expected 0-9", p.Error.Error())
+
+	result, _ = runParser("12345", Repeat(1, -1, Chars("0-9", 1, 1)))
+	require.Len(t, result.Child, 5)
+}
+
+func TestRepeatWith(t *testing.T) {
+	t.Run("keeps separators", func(t *testing.T) {
+		parser := RepeatWith(Chars("a-z"), RepeatOptions{Separator: Chars("+-", 1, 1), KeepSeparators: true})
+		result, p := runParser("a+b-c-", parser)
+		assertSequence(t, result, "a", "+", "b", "-", "c")
+		require.Equal(t, "-", p.Get())
+
+		parser = RepeatWith(Chars("a-z"), RepeatOptions{Separator: ";", Trailing: TrailingRequired, KeepSeparators: true})
+		result, p = runParser("a;b;c", parser)
+		assertSequence(t, result, "a", ";", "b", ";")
+		require.Equal(t, "c", p.Get())
+
+		parser = RepeatWith(Chars("a-z"), RepeatOptions{Separator: ",", Trailing: TrailingOptional, KeepSeparators: true})
+		result, _ = runParser("a,b,", parser)
+		assertSequence(t, result, "a", ",", "b", ",")
+	})
+
+	t.Run("at most max", func(t *testing.T) {
+		parser := RepeatWith(Chars("a-z"), RepeatOptions{Max: 2, Separator: ","})
+		result, p := runParser("a,b,c", parser)
+		assertSequence(t, result, "a", "b")
+		require.Equal(t, ",c", p.Get())
+
+		parser = RepeatWith(Chars("a-z"), RepeatOptions{Max: 2, Separator: ",", Trailing: TrailingOptional})
+		result, p = runParser("a,b,c", parser)
+		assertSequence(t, result, "a", "b")
+		require.Equal(t, "c", p.Get())
+	})
+
+	t.Run("describes itself", func(t *testing.T) {
+		g := Describe(RepeatWith(Chars("a-z"), RepeatOptions{Min: 1, Separator: ",", Trailing: TrailingRequired}))
+		require.Equal(t, GrammarMany, g.Kind)
+		require.Equal(t, 1, g.Min)
+		require.Equal(t, -1, g.Max)
+		require.Equal(t, TrailingRequired, g.Trailing)
+		require.Equal(t, GrammarExact, g.Separator().Kind)
+
+		g = Describe(Count(3, "a"))
+		require.Equal(t, "Count(3)", g.Name)
+		require.Equal(t, 3, g.Min)
+		require.Equal(t, 3, g.Max)
+		require.Equal(t, TrailingOptional, Describe(ZeroOrMore("a", ",")).Trailing)
+	})
+}
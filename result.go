@@ -18,6 +18,10 @@ type Result struct {
 	Input  string
 	Start  int
 	End    int
+	// Label is set by Named, see Query
+	Label string
+	// Kind is set by WithKind, see Walk
+	Kind string
 }
 
 func copyResult(dst, src *Result) {
@@ -27,6 +31,8 @@ func copyResult(dst, src *Result) {
 	dst.Input = src.Input
 	dst.Start = src.Start
 	dst.End = src.End
+	dst.Label = src.Label
+	dst.Kind = src.Kind
 }
 
 func NewResult(input string) *Result {
@@ -0,0 +1,144 @@
+package goparsify
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RunOptions configures a Runner. The zero value runs like Run.
+type RunOptions struct {
+	// WS is the whitespace parser, UnicodeWhitespace if nil
+	WS VoidParser
+	// Partial allows input to be left over after the parser matches, instead of it being an UnparsedInputError
+	Partial bool
+	// User is State.User at the start of every run. It is shared by every run, so it must not be changed in place.
+	User interface{}
+	// Tracer traces every run, see Instrument. A Tracer can only follow one parse at a time, so runs wait for
+	// each other while it is set.
+	Tracer *Tracer
+	// MaxInput, if set, is the most bytes of input that will be parsed. Anything longer is an error straight away.
+	MaxInput int
+	// MaxDepth, if set, is how many recursive parsers (given as a *Parser) can be nested inside of each other,
+	// so deeply nested input is an error rather than using up the stack. The parse stops as soon as it is reached,
+	// without trying any alternatives.
+	MaxDepth int
+	// MaxErrors, if set, is how many errors the parse can recover from, eg by trying the next alternative of an Any
+	// or by ending a repetition, so input that makes a grammar backtrack a lot is an error rather than taking
+	// forever. It stops like MaxDepth does.
+	MaxErrors int
+}
+
+// Runner runs a parser over many inputs with the same options, reusing the memory results are built in
+// between runs. It is safe to use from multiple goroutines.
+//
+// The Result tree is recycled as soon as a run finishes, so Map callbacks must not keep references to
+// *Result nodes or their .Child slices in the value they return. Copying what they need out, like the
+// json parser does, is fine.
+type Runner struct {
+	parser  Parser
+	opts    RunOptions
+	states  sync.Pool
+	tracing sync.Mutex
+}
+
+// NewRunner returns a Runner that runs parser with the given options
+func NewRunner(parser Parserish, opts RunOptions) *Runner {
+	if opts.WS == nil {
+		opts.WS = UnicodeWhitespace
+	}
+	r := &Runner{parser: Parsify(parser), opts: opts}
+	r.states.New = func() interface{} { return &State{} }
+	return r
+}
+
+// Reusable is the Runner returned by Parser.Reuse
+type Reusable = Runner
+
+// Reuse returns a Runner that runs p with the given whitespace parser, which defaults to UnicodeWhitespace
+// like Run. It is NewRunner for callers that dont need any other options.
+func (p Parser) Reuse(ws ...VoidParser) *Reusable {
+	opts := RunOptions{}
+	if len(ws) > 0 {
+		opts.WS = ws[0]
+	}
+	return NewRunner(p, opts)
+}
+
+// Run is Run using memory left over from earlier runs
+func (r *Runner) Run(input string) (result interface{}, err error) {
+	return r.run(input, r.opts.User)
+}
+
+// RunWithUser is Run, starting with user as State.User instead of RunOptions.User
+func (r *Runner) RunWithUser(input string, user interface{}) (result interface{}, err error) {
+	return r.run(input, user)
+}
+
+func (r *Runner) run(input string, user interface{}) (result interface{}, err error) {
+	if r.opts.MaxInput > 0 && len(input) > r.opts.MaxInput {
+		return nil, &Error{pos: r.opts.MaxInput, expected: fmt.Sprintf("at most %d bytes of input", r.opts.MaxInput)}
+	}
+	if r.opts.Tracer != nil {
+		r.tracing.Lock()
+		defer r.tracing.Unlock()
+	}
+
+	tracer := r.opts.Tracer
+	if tracer == nil {
+		tracer = defaultTracer()
+	}
+
+	ps := r.states.Get().(*State)
+	*ps = State{Input: input, WS: r.opts.WS, User: user, Tracer: tracer, maxDepth: r.opts.MaxDepth, maxErrors: r.opts.MaxErrors, arena: ps.arena}
+	ps.arena.reset(arenaMark{})
+
+	ret := Result{Input: input}
+	err = r.parse(ps, &ret)
+
+	r.states.Put(ps)
+	return ret.Result, err
+}
+
+func (r *Runner) parse(ps *State, node *Result) (err error) {
+	if ps.maxDepth > 0 || ps.maxErrors > 0 {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				limit, ok := recovered.(limitExceeded)
+				if !ok {
+					panic(recovered)
+				}
+				err = &limit.err
+			}
+		}()
+	}
+
+	r.parser(ps, node)
+	ps.WS(ps)
+
+	if ps.Errored() || (ps.Get() != "" && !r.opts.Partial) {
+		return ps.parseError()
+	}
+	return nil
+}
+
+// limitExceeded is panicked with when State.maxDepth or State.maxErrors is reached, so the parse stops right away
+// instead of backtracking into alternatives that would likely nest just as deeply. Runner recovers it.
+type limitExceeded struct {
+	err Error
+}
+
+// enter is called by recursive parsers before they run, see Parsify
+func (s *State) enter() {
+	s.depth++
+	if s.depth > s.maxDepth {
+		panic(limitExceeded{Error{pos: s.Pos, expected: fmt.Sprintf("at most %d levels of nesting", s.maxDepth)}})
+	}
+}
+
+// recovered counts an error at pos being recovered from, when they are limited to maxErrors
+func (s *State) recovered(pos int) {
+	s.errors++
+	if s.errors > s.maxErrors {
+		panic(limitExceeded{Error{pos: pos, expected: fmt.Sprintf("at most %d errors", s.maxErrors)}})
+	}
+}
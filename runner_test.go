@@ -0,0 +1,166 @@
+package goparsify
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReuse(t *testing.T) {
+	parser := Seq("(", ZeroOrMore(Any(NumberLit(), Chars("a-z")), ","), ")").Map(func(n *Result) {
+		sum := int64(0)
+		for _, child := range n.Child[1].Child {
+			if i, ok := child.Result.(int64); ok {
+				sum += i
+			}
+		}
+		n.Result = sum
+	})
+	reusable := parser.Reuse()
+
+	t.Run("matches Run", func(t *testing.T) {
+		for _, input := range []string{"(1, 2, 3)", "(a, 1, b, 2)", "()", "(1, 2", "(1) x", "\t( 4 ,5 )\n"} {
+			expected, expectedErr := Run(parser, input)
+			result, err := reusable.Run(input)
+			require.Equal(t, expected, result, input)
+			require.Equal(t, expectedErr, err, input)
+		}
+	})
+
+	t.Run("errors arent shared", func(t *testing.T) {
+		_, err1 := reusable.Run("(1, 2")
+		_, err2 := reusable.Run("(a b)")
+		require.NotEqual(t, err1.Error(), err2.Error())
+	})
+
+	t.Run("whitespace", func(t *testing.T) {
+		_, err := parser.Reuse(NoWhitespace).Run("(1, 2)")
+		require.Error(t, err)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					input := "(" + strconv.Itoa(i) + ", " + strconv.Itoa(j) + ", x)"
+					result, err := reusable.Run(input)
+					require.NoError(t, err)
+					require.Equal(t, int64(i+j), result)
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+}
+
+func TestRunner(t *testing.T) {
+	var list Parser
+	list = Seq("[", ZeroOrMore(Any(NumberLit(), &list), ","), "]").Map(func(n *Result) { n.Result = len(n.Child[1].Child) })
+
+	t.Run("defaults match Run", func(t *testing.T) {
+		runner := NewRunner(&list, RunOptions{})
+		for _, input := range []string{"[1, [2, 3]]", "[1, 2", "[] x", " [ ] "} {
+			expected, expectedErr := Run(&list, input)
+			result, err := runner.Run(input)
+			require.Equal(t, expected, result, input)
+			require.Equal(t, expectedErr, err, input)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		result, err := NewRunner(&list, RunOptions{Partial: true}).Run("[1, 2] trailing")
+		require.NoError(t, err)
+		require.Equal(t, 2, result)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		user := MapState("x", func(ps *State, n *Result) { n.Result = ps.User })
+		runner := NewRunner(user, RunOptions{User: "default"})
+
+		result, _ := runner.Run("x")
+		require.Equal(t, "default", result)
+		result, _ = runner.RunWithUser("x", "mine")
+		require.Equal(t, "mine", result)
+	})
+
+	t.Run("max input", func(t *testing.T) {
+		runner := NewRunner(&list, RunOptions{MaxInput: 6})
+		_, err := runner.Run("[1, 2]")
+		require.NoError(t, err)
+		_, err = runner.Run("[1, 22]")
+		require.Equal(t, "offset 6: expected at most 6 bytes of input", err.Error())
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		runner := NewRunner(&list, RunOptions{MaxDepth: 3})
+		_, err := runner.Run("[[[1]]]")
+		require.NoError(t, err)
+		_, err = runner.Run("[[[[1]]]]")
+		require.Equal(t, "offset 3: expected at most 3 levels of nesting", err.Error())
+
+		// the state is reset for the next run
+		_, err = runner.Run("[[[1]]]")
+		require.NoError(t, err)
+
+		_, err = NewRunner(&list, RunOptions{MaxDepth: 1000}).Run(strings.Repeat("[", 2000) + strings.Repeat("]", 2000))
+		require.Equal(t, "offset 1000: expected at most 1000 levels of nesting", err.Error())
+	})
+
+	t.Run("max errors", func(t *testing.T) {
+		runner := NewRunner(&list, RunOptions{MaxErrors: 5})
+		_, err := runner.Run("[1, [2]]")
+		require.NoError(t, err)
+		_, err = runner.Run("[1, [2], [[3]], [[[4]]]]")
+		require.Equal(t, "offset 13: expected at most 5 errors", err.Error())
+
+		// the count starts again for the next run
+		_, err = runner.Run("[1, [2]]")
+		require.NoError(t, err)
+	})
+
+	t.Run("tracer after a limit", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		tracer := NewTracer(buf)
+		runner := NewRunner(&list, RunOptions{Tracer: tracer, MaxDepth: 3})
+		_, err := runner.Run("[[[[1]]]]")
+		require.Error(t, err)
+		require.Empty(t, tracer.active)
+		require.Empty(t, tracer.pendingOpenLog)
+
+		buf.Reset()
+		_, err = runner.Run("[1]")
+		require.NoError(t, err)
+		require.Regexp(t, `^\S+ \| \[1\] +\| list \{`, buf.String())
+	})
+
+	t.Run("other panics arent recovered", func(t *testing.T) {
+		panics := Parser(func(ps *State, node *Result) { panic("oops") })
+		require.PanicsWithValue(t, "oops", func() { NewRunner(panics, RunOptions{MaxDepth: 10}).Run("") })
+	})
+
+	t.Run("tracer", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		tracer := NewTracer(buf)
+		runner := NewRunner(Seq(Chars("a-z"), "!"), RunOptions{Tracer: tracer})
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := runner.Run("hi!")
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		require.NotEmpty(t, tracer.Stats())
+		require.Contains(t, buf.String(), "hi")
+	})
+}
@@ -12,13 +12,59 @@ type State struct {
 	Input string
 	// An offset into the string, pointing to the current tip
 	Pos int
-	// Do not backtrack past this point
+	// Do not backtrack past this point, see Cut
 	Cut int
 	// Error is a secondary return channel from parsers, but used so heavily
 	// in backtracking that it has been inlined to avoid allocations.
 	Error Error
 	// Called to determine what to ignore when WS is called, or when WS fires
 	WS VoidParser
+	// FoldCase makes Exact, and strings given as parsers, match regardless of case. See FoldCase.
+	FoldCase bool
+	// User is data for the grammar itself, eg a symbol table or feature flags, set with RunWithUser. Combinators
+	// put it back when they backtrack, so set a new value rather than changing the old one in place. See GetUser.
+	User interface{}
+	// Tracer, when set, collects logs and timings for every parser run against this State.
+	// See Instrument.
+	Tracer *Tracer
+
+	arena    arena
+	captures *capture
+	// cutError is the furthest error that a cut stopped a choice from backtracking out of
+	cutError Error
+	// trailingSep is one past where the last separator a TrailingNever repetition gave back starts, see parseError
+	trailingSep int
+	// tokens are the tokens from a Lexer, matched by Tok. tokenHint is the one that is most likely to be next.
+	tokens    []Token
+	tokenHint int
+	// depth is how many recursive parsers are running, limited to maxDepth by a Runner, see State.enter
+	depth, maxDepth int
+	// errors is how many errors have been recovered from, limited to maxErrors by a Runner, see State.recovered
+	errors, maxErrors int
+}
+
+// stateMark is everything combinators put back when they backtrack, see State.mark
+type stateMark struct {
+	arena    arenaMark
+	captures *capture
+	user     interface{}
+}
+
+// mark remembers the results, captures and user data made so far, so reset can forget everything after it
+func (s *State) mark() stateMark {
+	return stateMark{arena: s.arena.mark(), captures: s.captures, user: s.User}
+}
+
+func (s *State) reset(m stateMark) {
+	s.arena.reset(m.arena)
+	s.captures = m.captures
+	s.User = m.user
+}
+
+// GetUser returns State.User as a T, or the zero T if it isnt one
+func GetUser[T any](s *State) T {
+	user, _ := s.User.(T)
+	return user
 }
 
 // ASCIIWhitespace matches any of the standard whitespace characters. It is faster
@@ -54,8 +100,9 @@ func NoWhitespace(s *State) {
 // NewState creates a new State from a string
 func NewState(input string) *State {
 	return &State{
-		Input: input,
-		WS:    UnicodeWhitespace,
+		Input:  input,
+		WS:     UnicodeWhitespace,
+		Tracer: defaultTracer(),
 	}
 }
 
@@ -96,6 +143,9 @@ func (s *State) ErrorHere(expected string) {
 // Recover from the current error. Often called by combinators that can match
 // when one of their children succeed, but others have failed.
 func (s *State) Recover() {
+	if s.maxErrors > 0 && s.Errored() {
+		s.recovered(s.Error.pos)
+	}
 	s.Error.expected = ""
 }
 
@@ -59,3 +59,86 @@ func TestWhitespaces(t *testing.T) {
 	_, err = Run(p, "hello world\u2005!", UnicodeWhitespace)
 	require.NoError(t, err)
 }
+
+func TestState_User(t *testing.T) {
+	// the c typedef problem, a * b is a declaration if a is a type and a multiplication otherwise
+	type scope struct {
+		typedef string
+		prev    *scope
+	}
+	isType := func(s *scope, name string) bool {
+		for ; s != nil; s = s.prev {
+			if s.typedef == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	ident := Chars("a-z")
+	typedef := MapState(Seq("typedef", ident, ";"), func(ps *State, n *Result) {
+		ps.User = &scope{typedef: n.Child[1].Token, prev: GetUser[*scope](ps)}
+		n.Result = "typedef"
+	})
+	typeName := Parser(func(ps *State, node *Result) {
+		startpos := ps.Pos
+		ident(ps, node)
+		if !ps.Errored() && !isType(GetUser[*scope](ps), node.Token) {
+			ps.Pos = startpos
+			ps.ErrorHere("type name")
+		}
+	})
+	statement := Any(typedef, Bind(Seq(typeName, "*", ident, ";"), "declaration"), Bind(Seq(ident, "*", ident, ";"), "multiplication"))
+	program := Map(ZeroOrMore(statement), func(n *Result) {
+		statements := []interface{}{}
+		for _, child := range n.Child {
+			statements = append(statements, child.Result)
+		}
+		n.Result = statements
+	})
+
+	t.Run("typedefs", func(t *testing.T) {
+		result, err := Run(program, "a * b; typedef a; a * b; c * d;")
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"multiplication", "typedef", "declaration", "multiplication"}, result)
+	})
+
+	t.Run("run with user", func(t *testing.T) {
+		result, err := RunWithUser(program, "c * d;", &scope{typedef: "c"})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"declaration"}, result)
+	})
+
+	t.Run("get user", func(t *testing.T) {
+		ps := NewState("")
+		require.Nil(t, GetUser[*scope](ps))
+		ps.User = "flags"
+		require.Equal(t, "flags", GetUser[string](ps))
+		require.Equal(t, 0, GetUser[int](ps))
+	})
+
+	// changes the user data, then fails
+	change := Parser(func(ps *State, node *Result) {
+		ps.User = "changed"
+		ps.ErrorHere("change")
+	})
+	user := MapState("a", func(ps *State, n *Result) { n.Result = ps.User })
+
+	for name, parser := range map[string]Parser{
+		"any":   Seq(Any(change, "x"), user),
+		"maybe": Seq("x", Maybe(change), user),
+		"many":  Seq("x", ZeroOrMore(change), user),
+		"sep":   Seq(ZeroOrMore("x", change), user),
+		"seq":   Seq(Any(Seq("x", change), "x"), user),
+		"chain": Seq(Any(ChainState("x", func(ps *State, prevN *Result) Parserish { return change }), "x"), user),
+	} {
+		t.Run("backtracking "+name, func(t *testing.T) {
+			ps := NewState("x a")
+			ps.User = "original"
+			result := Result{}
+			parser(ps, &result)
+			require.False(t, ps.Errored())
+			require.Equal(t, "original", result.Child[len(result.Child)-1].Result)
+		})
+	}
+}
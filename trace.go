@@ -0,0 +1,338 @@
+package goparsify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ajitid/goparsify/debug"
+)
+
+// parserInfo describes a parser created by NewParser. It is only made the first time the parser is traced or
+// covered, and the definition is only resolved into a variable name and location the first time it is needed.
+//
+// Parsers created at the same place (eg inside a Chain callback) share a parserInfo, see lookupParser.
+type parserInfo struct {
+	match   string
+	callers []uintptr
+	resolve sync.Once
+	site    debug.Site
+
+	// coverage counters, only updated while coverage is enabled
+	listed   atomic.Bool
+	calls    int64
+	matches  int64
+	branches []int64
+}
+
+// registry holds the parserInfo of every parser that has been traced or covered, keyed by what it matches and where
+// it was made
+var registry = struct {
+	sync.Mutex
+	byKey map[string]*parserInfo
+	order []*parserInfo
+}{byKey: map[string]*parserInfo{}}
+
+// info returns the parserInfo of g, it is looked up the first time g is traced or covered
+func (g *Grammar) info() *parserInfo {
+	g.infoOnce.Do(func() {
+		g.pinfo = lookupParser(g)
+	})
+	return g.pinfo
+}
+
+// lookupParser returns the parserInfo for g. Parsers that dont know where they were created get their own,
+// which goes away with them.
+func lookupParser(g *Grammar) *parserInfo {
+	branches := 0
+	if g.Kind == GrammarAny {
+		branches = len(g.parsers)
+	}
+	if g.callers == nil {
+		return newParserInfo(g.Name, branches, nil)
+	}
+
+	key := make([]byte, 0, len(g.Name)+8*(len(g.callers)+1))
+	key = append(key, g.Name...)
+	key = binary.LittleEndian.AppendUint64(key, uint64(branches))
+	for _, pc := range g.callers {
+		key = binary.LittleEndian.AppendUint64(key, uint64(pc))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if info, ok := registry.byKey[string(key)]; ok {
+		return info
+	}
+	info := newParserInfo(g.Name, branches, g.callers)
+	registry.byKey[string(key)] = info
+	return info
+}
+
+func newParserInfo(match string, branches int, callers []uintptr) *parserInfo {
+	info := &parserInfo{match: match, callers: callers}
+	if branches > 0 {
+		info.branches = make([]int64, branches)
+	}
+	return info
+}
+
+func (pi *parserInfo) definition() debug.Site {
+	pi.resolve.Do(func() {
+		pi.site = debug.Locate(pi.callers)
+		pi.callers = nil
+	})
+	return pi.site
+}
+
+// cover lists g in the coverage report the first time it is covered, along with everything underneath it so
+// parsers that are never called show up too
+func (g *Grammar) cover() *parserInfo {
+	info := g.info()
+	if !info.listed.CompareAndSwap(false, true) {
+		return info
+	}
+
+	registry.Lock()
+	registry.order = append(registry.order, info)
+	registry.Unlock()
+
+	for _, child := range g.Children() {
+		// parsers that werent created with NewParser cant be counted
+		if child.Name != "" {
+			child.cover()
+		}
+	}
+	if sep := g.Separator(); sep != nil && sep.Name != "" {
+		sep.cover()
+	}
+	return info
+}
+
+// tracedCall is a parser being traced, see grammarFunc.enter
+// tracedCall is a call to a parser that is being traced or covered
+type tracedCall struct {
+	tracer  *Tracer
+	grammar *Grammar
+	stats   *ParserStats
+	ps      *State
+	node    *Result
+}
+
+// enter starts tracing a call of the parser g describes, with the Tracer on the State. It has to be left when the
+// parser returns, even if it panics, so parsers defer that.
+func (g grammarFunc) enter(ps *State, node *Result) tracedCall {
+	t := ps.Tracer
+	described := g.grammar()
+	call := tracedCall{tracer: t, grammar: described, ps: ps, node: node}
+	if t != coverageTracer {
+		call.stats = t.start(described, ps)
+	}
+	return call
+}
+
+func (c tracedCall) leave() {
+	if c.stats != nil {
+		c.tracer.finish(c.stats, c.ps, c.node)
+	}
+	if covering.Load() {
+		info := c.grammar.cover()
+		atomic.AddInt64(&info.calls, 1)
+		if !c.ps.Errored() {
+			atomic.AddInt64(&info.matches, 1)
+		}
+	}
+}
+
+// matchedBranch records that branch i of an Any matched
+func (g *Grammar) matchedBranch(i int) {
+	if covering.Load() {
+		atomic.AddInt64(&g.cover().branches[i], 1)
+	}
+}
+
+// ParserStats are the timings collected by a Tracer for a single parser
+type ParserStats struct {
+	// Var is the name of the variable the parser was assigned to, if it could be found
+	Var string
+	// Match is the description given to NewParser, eg "Seq()" or the literal being matched
+	Match string
+	// Location is the file:line the parser was defined on
+	Location string
+	// Cumulative is the total time spent in this parser, including its children
+	Cumulative time.Duration
+	// Self is the time spent in this parser, excluding its children
+	Self time.Duration
+	// Calls is the number of times the parser was invoked
+	Calls int
+	// Errors is the number of invocations that failed
+	Errors int
+}
+
+type traceFrame struct {
+	varName  string
+	start    time.Time
+	children time.Duration
+}
+
+// Tracer provides the same logging and timing information as building with -tags debug, but can be
+// switched on at runtime for a single parse by setting State.Tracer or by using Instrument.
+//
+// Parsers are logged by the variable they were assigned to, or by what they match when that cant be found. Custom
+// parsers are only seen when they were made with NewParser.
+//
+// A Tracer is not safe for concurrent use, give each parse its own.
+type Tracer struct {
+	// Log receives a line for every parser entered and exited. Leave it nil to only collect timings.
+	Log io.Writer
+
+	stats           map[*parserInfo]*ParserStats
+	order           []*parserInfo
+	active          []traceFrame
+	pendingOpenLog  string
+	longestLocation int
+}
+
+// NewTracer creates a Tracer that writes its log to w, which may be nil.
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{Log: w}
+}
+
+// Instrument returns a parser that traces every parser underneath root using the given Tracer.
+//
+//	t := NewTracer(os.Stderr)
+//	result, err := Run(Instrument(root, t), input)
+//	t.DumpStats(os.Stderr)
+func Instrument(root Parserish, t *Tracer) Parser {
+	p := Parsify(root)
+	return wrapParser(p, func(ps *State, node *Result) {
+		oldTracer := ps.Tracer
+		ps.Tracer = t
+		p(ps, node)
+		ps.Tracer = oldTracer
+	})
+}
+
+func (t *Tracer) statsFor(info *parserInfo) *ParserStats {
+	if stats, ok := t.stats[info]; ok {
+		return stats
+	}
+	if t.stats == nil {
+		t.stats = map[*parserInfo]*ParserStats{}
+	}
+
+	site := info.definition()
+	varName, location := site.Var, site.Location()
+	if varName == "" {
+		location = ""
+	}
+	stats := &ParserStats{Var: varName, Match: info.match, Location: location}
+	t.stats[info] = stats
+	t.order = append(t.order, info)
+	if len(location) > t.longestLocation {
+		t.longestLocation = len(location)
+	}
+	return stats
+}
+
+func (t *Tracer) name(stats *ParserStats) string {
+	if stats.Var == "" || len(t.active) > 1 && t.active[len(t.active)-2].varName == stats.Var {
+		return stats.Match
+	}
+	return stats.Var
+}
+
+func (t *Tracer) logf(stats *ParserStats, ps *State, result *Result, msg string) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("%"+strconv.Itoa(t.longestLocation)+"s | ", stats.Location))
+	buf.WriteString(fmt.Sprintf("%-15s", ps.Preview(15)))
+	buf.WriteString(" | ")
+	buf.WriteString(strings.Repeat("  ", len(t.active)-1))
+	buf.WriteString(msg)
+	if ps.Errored() {
+		buf.WriteString(fmt.Sprintf(" did not find %s", ps.Error.expected))
+	} else if result != nil {
+		resultStr := strconv.Quote(result.String())
+		if len(resultStr) > 20 {
+			resultStr = resultStr[0:20]
+		}
+		buf.WriteString(fmt.Sprintf(" found %s", resultStr))
+	}
+	buf.WriteRune('\n')
+	return buf.String()
+}
+
+func (t *Tracer) logStart(stats *ParserStats, ps *State) {
+	if t.Log != nil {
+		if t.pendingOpenLog != "" {
+			fmt.Fprint(t.Log, t.pendingOpenLog)
+			t.pendingOpenLog = ""
+		}
+		t.pendingOpenLog = t.logf(stats, ps, nil, t.name(stats)+" {")
+	}
+}
+
+func (t *Tracer) logEnd(stats *ParserStats, ps *State, result *Result) {
+	if t.Log != nil {
+		if t.pendingOpenLog != "" {
+			fmt.Fprint(t.Log, t.logf(stats, ps, result, t.name(stats)))
+			t.pendingOpenLog = ""
+		} else {
+			fmt.Fprint(t.Log, t.logf(stats, ps, result, "}"))
+		}
+	}
+}
+
+func (t *Tracer) start(g *Grammar, ps *State) *ParserStats {
+	stats := t.statsFor(g.info())
+	t.active = append(t.active, traceFrame{varName: stats.Var, start: time.Now()})
+	t.logStart(stats, ps)
+	return stats
+}
+
+func (t *Tracer) finish(stats *ParserStats, ps *State, node *Result) {
+	t.logEnd(stats, ps, node)
+
+	frame := t.active[len(t.active)-1]
+	t.active = t.active[0 : len(t.active)-1]
+	elapsed := time.Since(frame.start)
+	if len(t.active) > 0 {
+		t.active[len(t.active)-1].children += elapsed
+	}
+
+	stats.Cumulative += elapsed
+	stats.Self += elapsed - frame.children
+	stats.Calls++
+	if ps.Errored() {
+		stats.Errors++
+	}
+}
+
+// Stats returns the timings for every parser that has been traced, slowest first
+func (t *Tracer) Stats() []ParserStats {
+	ret := make([]ParserStats, 0, len(t.order))
+	for _, info := range t.order {
+		ret = append(ret, *t.stats[info])
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Cumulative > ret[j].Cumulative
+	})
+	return ret
+}
+
+// DumpStats writes a table of the timings for each parser to w, in the same format as DumpDebugStats
+func (t *Tracer) DumpStats(w io.Writer) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "|             var name |              matches |      total time |       self time |      calls |     errors | location  ")
+	fmt.Fprintln(w, "| -------------------- | -------------------- | --------------- | --------------- | ---------- | ---------- | ----------")
+	for _, parser := range t.Stats() {
+		fmt.Fprintf(w, "| %20s | %20s | %15s | %15s | %10d | %10d | %s\n", parser.Var, parser.Match, parser.Cumulative.String(), parser.Self.String(), parser.Calls, parser.Errors, parser.Location)
+	}
+}
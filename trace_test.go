@@ -0,0 +1,60 @@
+package goparsify
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrument(t *testing.T) {
+	greeting := Seq("hello", Any("world", "there"))
+
+	t.Run("logs each parser", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		result, err := Run(Instrument(greeting, NewTracer(buf)), "hello there")
+		require.NoError(t, err)
+		require.Nil(t, result)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 7)
+		require.Contains(t, lines[0], "greeting {")
+		require.Contains(t, lines[0], "trace_test.go:")
+		require.Contains(t, lines[3], "world did not find world")
+		require.Contains(t, lines[4], `there found "there"`)
+		require.Contains(t, lines[6], `} found "[hello,there]"`)
+	})
+
+	t.Run("collects stats", func(t *testing.T) {
+		tracer := NewTracer(nil)
+		_, err := Run(Instrument(greeting, tracer), "hello world")
+		require.NoError(t, err)
+
+		calls := map[string]int{}
+		errors := map[string]int{}
+		for _, stats := range tracer.Stats() {
+			require.Equal(t, "greeting", stats.Var)
+			require.True(t, stats.Self <= stats.Cumulative)
+			calls[stats.Match] += stats.Calls
+			errors[stats.Match] += stats.Errors
+		}
+		require.Equal(t, map[string]int{"Seq()": 1, "hello": 1, "Any()": 1, "world": 1}, calls)
+		require.Equal(t, map[string]int{"Seq()": 0, "hello": 0, "Any()": 0, "world": 0}, errors)
+
+		buf := &bytes.Buffer{}
+		tracer.DumpStats(buf)
+		require.Contains(t, buf.String(), "Seq()")
+	})
+
+	t.Run("is scoped to the instrumented parser", func(t *testing.T) {
+		tracer := NewTracer(nil)
+		ps := NewState("hello world")
+		before := ps.Tracer
+		Instrument(greeting, tracer)(ps, NewResult(ps.Input))
+		require.Equal(t, before, ps.Tracer)
+
+		greeting(ps, NewResult(ps.Input))
+		require.Equal(t, 1, tracer.Stats()[0].Calls)
+	})
+}
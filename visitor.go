@@ -0,0 +1,66 @@
+package goparsify
+
+// WithKind sets the Kind of the result of parser, so a Visitor can dispatch on it. Unlike a Named label, the kind
+// says what a node is rather than where it is, eg every binary expression could be "binop".
+func WithKind(kind string, parser Parserish) Parser {
+	p := Parsify(parser)
+
+	return wrapParser(p, func(ps *State, node *Result) {
+		p(ps, node)
+		if ps.Errored() {
+			return
+		}
+		node.Kind = kind
+	})
+}
+
+// Visitor is a set of hooks called by Walk. Any of them may be nil.
+type Visitor struct {
+	// Pre is called before the children of a node are visited. Returning false skips the children and the
+	// post order hooks of that node.
+	Pre func(n *Result) bool
+	// Kinds are called after the children of a node with a matching Kind have been visited, before Post
+	Kinds map[string]func(n *Result)
+	// Post is called after the children of a node have been visited
+	Post func(n *Result)
+}
+
+// Walk visits n and then its descendants depth first, calling the hooks in v. Unlike Map this runs once parsing
+// is done, so it is a good place for desugaring or constant folding that needs the whole tree.
+//
+// Hooks may change the node they are given, or swap it for another with Replace. A node replaced in Pre has its
+// new children visited. Children are visited in place, so the tree given to Walk is modified.
+func Walk(n *Result, v Visitor) {
+	if v.Pre != nil && !v.Pre(n) {
+		return
+	}
+
+	for i := range n.Child {
+		Walk(&n.Child[i], v)
+	}
+
+	if n.Kind != "" && v.Kinds != nil {
+		if f := v.Kinds[n.Kind]; f != nil {
+			f(n)
+		}
+	}
+	if v.Post != nil {
+		v.Post(n)
+	}
+}
+
+// Replace swaps this node for with. The replacement keeps the span, input and label of the node it replaces
+// unless it sets its own, so errors can still be located and queries still find it.
+func (r *Result) Replace(with Result) {
+	if with.Start == 0 && with.End == 0 {
+		with.Start = r.Start
+		with.End = r.End
+	}
+	if with.Input == "" {
+		with.Input = r.Input
+	}
+	if with.Label == "" {
+		with.Label = r.Label
+	}
+	*r = with
+}
@@ -0,0 +1,103 @@
+package goparsify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithKind(t *testing.T) {
+	t.Run("sets kind on matches", func(t *testing.T) {
+		node, _ := runParser("1", WithKind("number", NumberLit()))
+		require.Equal(t, "number", node.Kind)
+	})
+
+	t.Run("doesnt set kind on errors", func(t *testing.T) {
+		node, ps := runParser("a", WithKind("number", NumberLit()))
+		require.Equal(t, "", node.Kind)
+		require.True(t, ps.Errored())
+	})
+}
+
+func TestWalk(t *testing.T) {
+	var expr Parser
+	number := WithKind("number", NumberLit())
+	sum := WithKind("sum", Seq("(", Named("lhs", &expr), "+", Named("rhs", &expr), ")"))
+	neg := WithKind("neg", Seq("-", &expr))
+	expr = Any(number, sum, neg)
+
+	parse := func(input string) Result {
+		node, ps := runParser(input, expr)
+		require.False(t, ps.Errored())
+		return node
+	}
+
+	t.Run("folds constants bottom up", func(t *testing.T) {
+		node := parse("(1 + (2 + 3))")
+		Walk(&node, Visitor{Kinds: map[string]func(n *Result){
+			"sum": func(n *Result) {
+				n.Replace(Result{Kind: "number", Result: n.First("lhs").Result.(int64) + n.First("rhs").Result.(int64)})
+			},
+		}})
+
+		require.Equal(t, "number", node.Kind)
+		require.Equal(t, int64(6), node.Result)
+		require.Equal(t, 0, node.Start)
+		require.Equal(t, 13, node.End)
+		require.Empty(t, node.Child)
+	})
+
+	t.Run("desugars in pre order", func(t *testing.T) {
+		node := parse("-(1 + 2)")
+		visited := []string{}
+		Walk(&node, Visitor{
+			Pre: func(n *Result) bool {
+				if n.Kind == "neg" {
+					// -x is sugar for (0 - x)
+					rhs := n.Child[1]
+					rhs.Label = "rhs"
+					n.Replace(Result{Kind: "sub", Child: []Result{
+						{Label: "lhs", Kind: "number", Result: int64(0)},
+						rhs,
+					}})
+				}
+				return true
+			},
+			Post: func(n *Result) {
+				if n.Kind != "" {
+					visited = append(visited, n.Kind)
+				}
+			},
+		})
+
+		require.Equal(t, "sub", node.Kind)
+		require.Equal(t, "sum", node.First("rhs").Kind)
+		require.Equal(t, []string{"number", "number", "number", "sum", "sub"}, visited)
+	})
+
+	t.Run("pre can skip children", func(t *testing.T) {
+		node := parse("(1 + (2 + 3))")
+		sums := 0
+		Walk(&node, Visitor{
+			Pre: func(n *Result) bool { return n.Kind != "sum" || n.Start == 0 },
+			Kinds: map[string]func(n *Result){
+				"sum": func(n *Result) { sums++ },
+			},
+		})
+		require.Equal(t, 1, sums)
+	})
+}
+
+func TestReplace(t *testing.T) {
+	t.Run("keeps span input and label", func(t *testing.T) {
+		node := Result{Input: "abc", Start: 1, End: 2, Label: "x", Kind: "old", Token: "b"}
+		node.Replace(Result{Result: 42})
+		require.Equal(t, Result{Input: "abc", Start: 1, End: 2, Label: "x", Result: 42}, node)
+	})
+
+	t.Run("uses span and label of replacement", func(t *testing.T) {
+		node := Result{Input: "abc", Start: 1, End: 2, Label: "x"}
+		node.Replace(Result{Start: 0, End: 3, Label: "y"})
+		require.Equal(t, Result{Input: "abc", Start: 0, End: 3, Label: "y"}, node)
+	})
+}